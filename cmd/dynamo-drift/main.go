@@ -0,0 +1,94 @@
+// Command dynamo-drift compares DynamoDB tables from the CloudFormation template with the existing ones
+// and reports how they differ. It exits with status 1 when any table drifted.
+//
+//	dynamo-drift -template template.yml [-param Env=dev...] [-endpoint http://localhost:8000] [-plan | -migrate] [Name...]
+//
+// With -plan it prints UpdateTable operations, that would migrate drifted tables, without running them.
+// With -migrate it runs them, creating new global secondary indexes one at a time.
+//
+// Tables are looked up by logical names or table names. Parameters of the template are set with -param.
+//
+// Without -endpoint it talks to DYNAMODB_ENDPOINT or DynamoDB local at http://localhost:8000.
+// Real AWS, with the default credentials and region, has to be chosen explicitly with -endpoint aws.
+package main
+
+import (
+	"context"
+	"dynamodb-with-go/internal/cli"
+	"dynamodb-with-go/pkg/dynamo"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	template := flag.String("template", "template.yml", "CloudFormation template with the tables")
+	endpoint := flag.String("endpoint", "", cli.EndpointUsage)
+	plan := flag.Bool("plan", false, "print migration plan of drifted tables (dry run)")
+	migrate := flag.Bool("migrate", false, "migrate drifted tables")
+	params := cli.Parameters{}
+	flag.Var(params, "param", "template parameter `Name=Value`, can be repeated")
+	flag.Parse()
+
+	mode := report
+	switch {
+	case *migrate:
+		mode = apply
+	case *plan:
+		mode = dryRun
+	}
+	drifted, err := run(context.Background(), *template, params, *endpoint, mode, flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if drifted {
+		os.Exit(1)
+	}
+}
+
+type mode int
+
+const (
+	report mode = iota
+	dryRun
+	apply
+)
+
+func run(ctx context.Context, template string, params cli.Parameters, endpoint string, m mode, names []string) (bool, error) {
+	tables, err := cli.LoadTables(template, params, names)
+	if err != nil {
+		return false, err
+	}
+	db, err := cli.Client(ctx, endpoint)
+	if err != nil {
+		return false, err
+	}
+	drifted := false
+	for _, table := range tables {
+		diffs, err := cli.ReportDrift(ctx, os.Stdout, db, table)
+		if err != nil {
+			return drifted, err
+		}
+		if len(diffs) == 0 {
+			continue
+		}
+		drifted = true
+		if m == report || diffs[0].Kind == dynamo.DiffMissingTable {
+			continue
+		}
+
+		plan, err := dynamo.PlanMigration(ctx, db, table.AWSDynamoDBTable)
+		if err != nil {
+			return drifted, err
+		}
+		fmt.Print(plan)
+		if m == apply {
+			if err := dynamo.ApplyMigration(ctx, db, plan); err != nil {
+				return drifted, err
+			}
+			fmt.Printf("table %q migrated\n", table.TableName)
+		}
+	}
+	return drifted, nil
+}
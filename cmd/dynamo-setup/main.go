@@ -0,0 +1,227 @@
+// Command dynamo-setup creates DynamoDB tables from the CloudFormation template (or Terraform module),
+// e.g. to prepare DynamoDB local for manual testing and demos.
+//
+//	dynamo-setup create   -template template.yml [-endpoint http://localhost:8000] [-param Env=dev...] [-wait] [Name...]
+//	dynamo-setup delete   ...
+//	dynamo-setup recreate ...
+//	dynamo-setup describe ...
+//	dynamo-setup diff     ...
+//
+// Commands work on all tables from the template, or the ones given by logical names or table names.
+// create and delete return once DynamoDB accepted the request, with -wait they wait until tables
+// are ACTIVE (and have TTL, point-in-time recovery and tags set) or gone. recreate always waits.
+// describe prints existing tables as CloudFormation template, diff reports how they differ from
+// the template and exits with status 1 when any table drifted.
+//
+// Without -endpoint it talks to DYNAMODB_ENDPOINT or DynamoDB local at http://localhost:8000.
+// Real AWS, with the default credentials and region, has to be chosen explicitly with -endpoint aws.
+package main
+
+import (
+	"context"
+	"dynamodb-with-go/internal/cli"
+	"dynamodb-with-go/pkg/dynamo"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+const usage = `usage: dynamo-setup <create|delete|recreate|describe|diff> [flags] [Name...]`
+
+var commands = map[string]func(ctx context.Context, s *setup) error{
+	"create":   create,
+	"delete":   remove,
+	"recreate": recreate,
+	"describe": describe,
+	"diff":     diff,
+}
+
+// errDrift makes diff exit with status 1.
+var errDrift = errors.New("tables drifted")
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n%s\n", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("dynamo-setup "+os.Args[1], flag.ExitOnError)
+	template := fs.String("template", "template.yml", "CloudFormation template (or Terraform file, module directory) with the tables")
+	endpoint := fs.String("endpoint", "", cli.EndpointUsage)
+	wait := fs.Bool("wait", false, "wait until tables are ACTIVE or deleted")
+	timeout := fs.Duration("timeout", 2*time.Minute, "how long to wait for tables")
+	params := cli.Parameters{}
+	fs.Var(params, "param", "template parameter `Name=Value`, can be repeated")
+	fs.Parse(os.Args[2:])
+
+	ctx := context.Background()
+	s, err := newSetup(ctx, *template, params, *endpoint, fs.Args())
+	if err == nil {
+		s.wait = *wait
+		s.timeout = *timeout
+		err = cmd(ctx, s)
+	}
+	if errors.Is(err, errDrift) {
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+type setup struct {
+	db      *dynamodb.Client
+	tables  []cli.Table
+	wait    bool
+	timeout time.Duration
+}
+
+func newSetup(ctx context.Context, template string, params cli.Parameters, endpoint string, names []string) (*setup, error) {
+	tables, err := cli.LoadTables(template, params, names)
+	if err != nil {
+		return nil, err
+	}
+	db, err := cli.Client(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &setup{db: db, tables: tables}, nil
+}
+
+func create(ctx context.Context, s *setup) error {
+	if s.wait {
+		tables := make([]cloudformation.AWSDynamoDBTable, 0, len(s.tables))
+		for _, table := range s.tables {
+			tables = append(tables, table.AWSDynamoDBTable)
+		}
+		created, err := dynamo.CreateTables(ctx, s.db, tables, dynamo.WithTimeout(s.timeout))
+		for _, name := range created {
+			fmt.Printf("table %q created\n", name)
+		}
+		return err
+	}
+
+	var errs dynamo.Errors
+	for _, table := range s.tables {
+		input, err := dynamo.CreateInput(table.AWSDynamoDBTable)
+		if err == nil {
+			_, err = s.db.CreateTable(ctx, &input)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not create table %q: %w", table.TableName, err))
+			continue
+		}
+		fmt.Printf("table %q is being created\n", table.TableName)
+		if table.TimeToLiveSpecification != nil || table.PointInTimeRecoverySpecification != nil || len(table.Tags) > 0 {
+			fmt.Fprintf(os.Stderr, "table %q: TTL, point-in-time recovery and tags are set with -wait only\n", table.TableName)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func remove(ctx context.Context, s *setup) error {
+	names := s.names()
+	if s.wait {
+		err := dynamo.DeleteTables(ctx, s.db, names, dynamo.WithTimeout(s.timeout))
+		if err == nil {
+			for _, name := range names {
+				fmt.Printf("table %q deleted\n", name)
+			}
+		}
+		return err
+	}
+
+	var errs dynamo.Errors
+	for _, name := range names {
+		_, err := s.db.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(name)})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not delete table %q: %w", name, err))
+			continue
+		}
+		fmt.Printf("table %q is being deleted\n", name)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// recreate deletes tables, that exist, and creates all of them again.
+func recreate(ctx context.Context, s *setup) error {
+	var existing []string
+	for _, name := range s.names() {
+		_, err := s.db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		existing = append(existing, name)
+	}
+	if err := dynamo.DeleteTables(ctx, s.db, existing, dynamo.WithTimeout(s.timeout)); err != nil {
+		return err
+	}
+	for _, name := range existing {
+		fmt.Printf("table %q deleted\n", name)
+	}
+	s.wait = true
+	return create(ctx, s)
+}
+
+func describe(ctx context.Context, s *setup) error {
+	var tables []cloudformation.AWSDynamoDBTable
+	for _, name := range s.names() {
+		table, err := dynamo.DescribeCloudFormation(ctx, s.db, name)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, table)
+	}
+	b, err := dynamo.Template(tables...).YAML()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(b)
+	return err
+}
+
+func diff(ctx context.Context, s *setup) error {
+	drifted := false
+	for _, table := range s.tables {
+		diffs, err := cli.ReportDrift(ctx, os.Stdout, s.db, table)
+		if err != nil {
+			return err
+		}
+		drifted = drifted || len(diffs) > 0
+	}
+	if drifted {
+		return errDrift
+	}
+	return nil
+}
+
+func (s *setup) names() []string {
+	names := make([]string, 0, len(s.tables))
+	for _, table := range s.tables {
+		names = append(names, table.TableName)
+	}
+	return names
+}
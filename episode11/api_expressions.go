@@ -2,6 +2,7 @@ package episode12
 
 import (
 	"context"
+	"dynamodb-with-go/pkg/dynamo"
 	"errors"
 	"fmt"
 
@@ -12,7 +13,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
-func GetItemCollectionV2(ctx context.Context, db *dynamodb.Client, table, pk string) ([]Item, error) {
+func GetItemCollectionV2(ctx context.Context, db dynamo.Client, table, pk string) ([]Item, error) {
 	expr, err := expression.NewBuilder().
 		WithKeyCondition(expression.KeyEqual(expression.Key("pk"), expression.Value(pk))).
 		Build()
@@ -20,25 +21,22 @@ func GetItemCollectionV2(ctx context.Context, db *dynamodb.Client, table, pk str
 	if err != nil {
 		return nil, err
 	}
-	out, err := db.Query(ctx, &dynamodb.QueryInput{
+	p := dynamo.NewQueryPaginator(db, &dynamodb.QueryInput{
 		KeyConditionExpression:    expr.KeyCondition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		TableName:                 aws.String(table),
 	})
-	if err != nil {
-		return nil, err
-	}
 
 	var items []Item
-	err = attributevalue.UnmarshalListOfMaps(out.Items, &items)
+	err = p.All(ctx, &items)
 	if err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
-func UpdateAWhenBAndUnsetBV2(ctx context.Context, db *dynamodb.Client, table string, k Key, newA, whenB string) (Item, error) {
+func UpdateAWhenBAndUnsetBV2(ctx context.Context, db dynamo.Client, table string, k Key, newA, whenB string) (Item, error) {
 	marshaledKey, err := attributevalue.MarshalMap(k)
 	if err != nil {
 		return Item{}, err
@@ -77,7 +75,7 @@ func UpdateAWhenBAndUnsetBV2(ctx context.Context, db *dynamodb.Client, table str
 	return i, nil
 }
 
-func PutIfNotExistsV2(ctx context.Context, db *dynamodb.Client, table string, k Key) error {
+func PutIfNotExistsV2(ctx context.Context, db dynamo.Client, table string, k Key) error {
 	marshaledKey, err := attributevalue.MarshalMap(k)
 	if err != nil {
 		return err
@@ -5,7 +5,6 @@ import (
 	"dynamodb-with-go/pkg/dynamo"
 	"testing"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 
@@ -13,20 +12,19 @@ import (
 )
 
 func insert(ctx context.Context, db *dynamodb.Client, table string, items ...Item) {
+	w := dynamo.NewBatchWriter(ctx, db)
 	for _, i := range items {
 		attrs, err := attributevalue.MarshalMap(i)
 		if err != nil {
 			panic(err)
 		}
-
-		_, err = db.PutItem(ctx, &dynamodb.PutItemInput{
-			Item:      attrs,
-			TableName: aws.String(table),
-		})
-		if err != nil {
+		if err := w.Put(table, attrs); err != nil {
 			panic(err)
 		}
 	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
 }
 
 func TestExpressions(t *testing.T) {
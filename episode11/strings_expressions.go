@@ -2,6 +2,7 @@ package episode12
 
 import (
 	"context"
+	"dynamodb-with-go/pkg/dynamo"
 	"errors"
 	"fmt"
 
@@ -11,8 +12,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
-func GetItemCollectionV1(ctx context.Context, db *dynamodb.Client, table, pk string) ([]Item, error) {
-	out, err := db.Query(ctx, &dynamodb.QueryInput{
+func GetItemCollectionV1(ctx context.Context, db dynamo.Client, table, pk string) ([]Item, error) {
+	p := dynamo.NewQueryPaginator(db, &dynamodb.QueryInput{
 		KeyConditionExpression: aws.String("#key = :value"),
 		ExpressionAttributeNames: map[string]string{
 			"#key": "pk",
@@ -22,19 +23,16 @@ func GetItemCollectionV1(ctx context.Context, db *dynamodb.Client, table, pk str
 		},
 		TableName: aws.String(table),
 	})
-	if err != nil {
-		return nil, err
-	}
 
 	var items []Item
-	err = attributevalue.UnmarshalListOfMaps(out.Items, &items)
+	err := p.All(ctx, &items)
 	if err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
-func UpdateAWhenBAndUnsetBV1(ctx context.Context, db *dynamodb.Client, table string, k Key, newA, whenB string) (Item, error) {
+func UpdateAWhenBAndUnsetBV1(ctx context.Context, db dynamo.Client, table string, k Key, newA, whenB string) (Item, error) {
 	marshaledKey, err := attributevalue.MarshalMap(k)
 	if err != nil {
 		return Item{}, err
@@ -70,7 +68,7 @@ func UpdateAWhenBAndUnsetBV1(ctx context.Context, db *dynamodb.Client, table str
 	return i, nil
 }
 
-func PutIfNotExistsV1(ctx context.Context, db *dynamodb.Client, table string, k Key) error {
+func PutIfNotExistsV1(ctx context.Context, db dynamo.Client, table string, k Key) error {
 	marshaledKey, err := attributevalue.MarshalMap(k)
 	if err != nil {
 		return err
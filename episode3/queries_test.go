@@ -16,11 +16,9 @@ import (
 func TestSingleFileFromDirectory(t *testing.T) {
 	ctx := context.Background()
 	tableName := "FileSystemTable"
-	db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml")
+	db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml", dynamo.WithFixtures("./fixtures.yml"))
 	defer cleanup()
 
-	insert(ctx, db, tableName)
-
 	out, err := db.GetItem(ctx, &dynamodb.GetItemInput{
 		Key: map[string]types.AttributeValue{
 			"directory": &types.AttributeValueMemberS{Value: "finances"},
@@ -39,11 +37,9 @@ func TestSingleFileFromDirectory(t *testing.T) {
 func TestAllFilesFromDirectory(t *testing.T) {
 	ctx := context.Background()
 	tableName := "FileSystemTable"
-	db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml")
+	db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml", dynamo.WithFixtures("./fixtures.yml"))
 	defer cleanup()
 
-	insert(ctx, db, tableName)
-
 	expr, err := expression.NewBuilder().
 		WithKeyCondition(
 			expression.KeyEqual(expression.Key("directory"), expression.Value("finances"))).
@@ -63,11 +59,9 @@ func TestAllFilesFromDirectory(t *testing.T) {
 func TestAllReportsBefore2019(t *testing.T) {
 	ctx := context.Background()
 	tableName := "FileSystemTable"
-	db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml")
+	db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml", dynamo.WithFixtures("./fixtures.yml"))
 	defer cleanup()
 
-	insert(ctx, db, tableName)
-
 	expr, err := expression.NewBuilder().
 		WithKeyCondition(
 			expression.KeyAnd(
@@ -97,19 +91,3 @@ type item struct {
 	Filename  string `dynamodbav:"filename"`
 	Size      string `dynamodbav:"size"`
 }
-
-func insert(ctx context.Context, db *dynamodb.Client, tableName string) {
-	item1 := item{Directory: "finances", Filename: "report2017.pdf", Size: "1MB"}
-	item2 := item{Directory: "finances", Filename: "report2018.pdf", Size: "1MB"}
-	item3 := item{Directory: "finances", Filename: "report2019.pdf", Size: "1MB"}
-	item4 := item{Directory: "finances", Filename: "report2020.pdf", Size: "2MB"}
-	item5 := item{Directory: "fun", Filename: "game1", Size: "4GB"}
-
-	for _, item := range []item{item1, item2, item3, item4, item5} {
-		attrs, _ := attributevalue.MarshalMap(&item)
-		db.PutItem(ctx, &dynamodb.PutItemInput{
-			TableName: aws.String(tableName),
-			Item:      attrs,
-		})
-	}
-}
@@ -16,11 +16,9 @@ import (
 func TestPhotosYoungerThan(t *testing.T) {
 	ctx := context.Background()
 	tableName := "FileSystemTable"
-	db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml")
+	db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml", dynamo.WithFixtures("./fixtures.json"))
 	defer cleanup()
 
-	insert(ctx, db, tableName)
-
 	expr, err := expression.NewBuilder().
 		WithKeyCondition(
 			expression.KeyAnd(
@@ -45,11 +43,9 @@ func TestPhotosYoungerThan(t *testing.T) {
 func TestPhotosFromTimeRange(t *testing.T) {
 	ctx := context.Background()
 	tableName := "FileSystemTable"
-	db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml")
+	db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml", dynamo.WithFixtures("./fixtures.json"))
 	defer cleanup()
 
-	insert(ctx, db, tableName)
-
 	expr, err := expression.NewBuilder().
 		WithKeyCondition(
 			expression.KeyAnd(
@@ -74,11 +70,9 @@ func TestPhotosFromTimeRange(t *testing.T) {
 func TestNewestPhoto(t *testing.T) {
 	ctx := context.Background()
 	tableName := "FileSystemTable"
-	db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml")
+	db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml", dynamo.WithFixtures("./fixtures.json"))
 	defer cleanup()
 
-	insert(ctx, db, tableName)
-
 	expr, err := expression.NewBuilder().
 		WithKeyCondition(expression.KeyEqual(expression.Key("directory"), expression.Value("photos"))).
 		Build()
@@ -108,18 +102,3 @@ type item struct {
 	Size      string    `dynamodbav:"size"`
 	CreatedAt time.Time `dynamodbav:"created_at"`
 }
-
-func insert(ctx context.Context, db *dynamodb.Client, tableName string) {
-	item1 := item{Directory: "photos", Filename: "bike.png", Size: "1.2MB", CreatedAt: time.Date(2017, 3, 4, 0, 0, 0, 0, time.UTC)}
-	item2 := item{Directory: "photos", Filename: "apartment.jpg", Size: "4MB", CreatedAt: time.Date(2018, 6, 25, 0, 0, 0, 0, time.UTC)}
-	item3 := item{Directory: "photos", Filename: "grandpa.png", Size: "3MB", CreatedAt: time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)}
-	item4 := item{Directory: "photos", Filename: "kids.png", Size: "3MB", CreatedAt: time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)}
-
-	for _, item := range []item{item1, item2, item3, item4} {
-		attrs, _ := attributevalue.MarshalMap(&item)
-		db.PutItem(ctx, &dynamodb.PutItemInput{
-			TableName: aws.String(tableName),
-			Item:      attrs,
-		})
-	}
-}
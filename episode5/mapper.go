@@ -2,6 +2,7 @@ package episode5
 
 import (
 	"context"
+	"dynamodb-with-go/pkg/dynamo"
 	"errors"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -14,12 +15,12 @@ import (
 
 // Mapper keeps Dynamo dependency.
 type Mapper struct {
-	db    *dynamodb.Client
+	db    dynamo.Client
 	table string
 }
 
 // NewMapper creates instance of Mapper.
-func NewMapper(client *dynamodb.Client, table string) *Mapper {
+func NewMapper(client dynamo.Client, table string) *Mapper {
 	return &Mapper{db: client, table: table}
 }
 
@@ -72,3 +73,31 @@ func (m *Mapper) Map(ctx context.Context, old string) (string, error) {
 	attributevalue.UnmarshalMap(out.Item, &ret)
 	return ret.NewID, nil
 }
+
+// Lookup returns new IDs of the old IDs, in the same order, reading them all with BatchGetItem.
+// Old IDs, that were never mapped, get empty new ID.
+func (m *Mapper) Lookup(ctx context.Context, olds ...string) ([]string, error) {
+	keys := make([]map[string]types.AttributeValue, 0, len(olds))
+	for _, old := range olds {
+		keys = append(keys, map[string]types.AttributeValue{
+			"old_id": &types.AttributeValueMemberS{Value: old},
+		})
+	}
+	results, err := dynamo.BatchGet(ctx, m.db, dynamo.BatchGetInput{TableName: m.table, Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+
+	news := make([]string, len(olds))
+	for i, r := range results {
+		if !r.Found {
+			continue
+		}
+		var ret mapping
+		if err := r.Decode(&ret); err != nil {
+			return nil, err
+		}
+		news[i] = ret.NewID
+	}
+	return news, nil
+}
@@ -47,4 +47,21 @@ func TestMapping(t *testing.T) {
 		assert.Equal(t, first, second)
 	})
 
+	t.Run("look up many legacy IDs at once", func(t *testing.T) {
+		ctx := context.Background()
+		tableName := "LegacyIDsTable"
+		db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml")
+		defer cleanup()
+
+		mapper := NewMapper(db, tableName)
+
+		first, err := mapper.Map(ctx, "123")
+		assert.NoError(t, err)
+		second, err := mapper.Map(ctx, "456")
+		assert.NoError(t, err)
+
+		news, err := mapper.Lookup(ctx, "456", "789", "123", "456")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{second, "", first, second}, news)
+	})
 }
@@ -2,6 +2,7 @@ package episode6
 
 import (
 	"context"
+	"dynamodb-with-go/pkg/dynamo"
 	"errors"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -14,12 +15,12 @@ import (
 
 // Mapper keeps Dynamo dependency.
 type Mapper struct {
-	db    *dynamodb.Client
+	db    dynamo.Client
 	table string
 }
 
 // NewMapper creates instance of Mapper.
-func NewMapper(client *dynamodb.Client, table string) *Mapper {
+func NewMapper(client dynamo.Client, table string) *Mapper {
 	return &Mapper{db: client, table: table}
 }
 
@@ -2,6 +2,7 @@ package sensors
 
 import (
 	"context"
+	"dynamodb-with-go/pkg/dynamo"
 	"errors"
 	"fmt"
 	"strings"
@@ -105,7 +106,7 @@ func (ri readingItem) asReading() Reading {
 	}
 }
 
-func NewManager(db *dynamodb.Client, table string) *sensorManager {
+func NewManager(db dynamo.Client, table string) *sensorManager {
 	return &sensorManager{db: db, table: table}
 }
 
@@ -115,7 +116,7 @@ type SensorsManager interface {
 }
 
 type sensorManager struct {
-	db    *dynamodb.Client
+	db    dynamo.Client
 	table string
 }
 
@@ -238,19 +239,19 @@ func (s *sensorManager) GetSensors(ctx context.Context, location Location) ([]st
 		return nil, err
 	}
 
-	out, err := s.db.Query(ctx, &dynamodb.QueryInput{
+	p := dynamo.NewQueryPaginator(s.db, &dynamodb.QueryInput{
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		KeyConditionExpression:    expr.KeyCondition(),
 		TableName:                 aws.String(s.table),
 	})
-	if err != nil {
+
+	var items []sensorItem
+	if err := p.All(ctx, &items); err != nil {
 		return nil, err
 	}
 	var ids []string
-	for _, item := range out.Items {
-		var si sensorItem
-		attributevalue.UnmarshalMap(item, &si)
+	for _, si := range items {
 		ids = append(ids, si.ID)
 	}
 	return ids, nil
@@ -47,6 +47,8 @@ func TestSensors(t *testing.T) {
 
 		err = manager.Register(ctx, sensor)
 		assert.EqualError(t, err, "already registered")
+
+		dynamo.AssertTable(t, ctx, db, tableName, "./testdata/register.golden.json")
 	})
 
 	t.Run("save new reading", func(t *testing.T) {
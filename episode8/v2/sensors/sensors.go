@@ -2,6 +2,7 @@ package sensors
 
 import (
 	"context"
+	"dynamodb-with-go/pkg/dynamo"
 	"errors"
 	"fmt"
 	"strings"
@@ -108,7 +109,7 @@ func (ri readingItem) asReading() Reading {
 	}
 }
 
-func NewManager(db *dynamodb.Client, table string) *sensorManager {
+func NewManager(db dynamo.Client, table string) *sensorManager {
 	return &sensorManager{db: db, table: table}
 }
 
@@ -118,7 +119,7 @@ type SensorsManager interface {
 }
 
 type sensorManager struct {
-	db    *dynamodb.Client
+	db    dynamo.Client
 	table string
 }
 
@@ -171,6 +172,34 @@ func (s *sensorManager) Get(ctx context.Context, id string) (Sensor, error) {
 	return si.asSensor(), nil
 }
 
+// GetMany reads sensors with BatchGetItem and returns them in the order of IDs.
+func (s *sensorManager) GetMany(ctx context.Context, ids ...string) ([]Sensor, error) {
+	keys := make([]map[string]types.AttributeValue, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: "SENSOR#" + id},
+			"sk": &types.AttributeValueMemberS{Value: "SENSORINFO"},
+		})
+	}
+	results, err := dynamo.BatchGet(ctx, s.db, dynamo.BatchGetInput{TableName: s.table, Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+
+	sensors := make([]Sensor, 0, len(results))
+	for i, r := range results {
+		if !r.Found {
+			return nil, fmt.Errorf("sensor %s not found", ids[i])
+		}
+		var si sensorItem
+		if err := r.Decode(&si); err != nil {
+			return nil, err
+		}
+		sensors = append(sensors, si.asSensor())
+	}
+	return sensors, nil
+}
+
 func (s *sensorManager) SaveReading(ctx context.Context, reading Reading) error {
 	attrs, err := attributevalue.MarshalMap(reading.asItem())
 	if err != nil {
@@ -226,21 +255,20 @@ func (s *sensorManager) GetSensors(ctx context.Context, location Location) ([]st
 		return nil, err
 	}
 
-	out, err := s.db.Query(ctx, &dynamodb.QueryInput{
+	p := dynamo.NewQueryPaginator(s.db, &dynamodb.QueryInput{
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		KeyConditionExpression:    expr.KeyCondition(),
 		TableName:                 aws.String(s.table),
 		IndexName:                 aws.String("ByLocation"),
 	})
-	if err != nil {
+
+	var items []sensorItem
+	if err := p.All(ctx, &items); err != nil {
 		return nil, err
 	}
 	var ids []string
-
-	for _, item := range out.Items {
-		var si sensorItem
-		attributevalue.UnmarshalMap(item, &si)
+	for _, si := range items {
 		ids = append(ids, strings.TrimLeft(si.ID, "SENSOR#"))
 	}
 	return ids, nil
@@ -36,6 +36,25 @@ func TestSensors(t *testing.T) {
 		assert.Equal(t, sensor, returned)
 	})
 
+	t.Run("get many sensors", func(t *testing.T) {
+		tableName := "SensorsTable"
+		db, cleanup := dynamo.SetupTable(t, ctx, tableName, "../template.yml")
+		defer cleanup()
+		manager := sensors.NewManager(db, tableName)
+
+		other := sensor
+		other.ID = "sensor-2"
+		assert.NoError(t, manager.Register(ctx, sensor))
+		assert.NoError(t, manager.Register(ctx, other))
+
+		returned, err := manager.GetMany(ctx, "sensor-2", "sensor-1")
+		assert.NoError(t, err)
+		assert.Equal(t, []sensors.Sensor{other, sensor}, returned)
+
+		_, err = manager.GetMany(ctx, "sensor-1", "sensor-3")
+		assert.EqualError(t, err, "sensor sensor-3 not found")
+	})
+
 	t.Run("do not allow to register many times", func(t *testing.T) {
 		tableName := "SensorsTable"
 		db, cleanup := dynamo.SetupTable(t, ctx, tableName, "../template.yml")
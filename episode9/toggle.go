@@ -2,6 +2,7 @@ package episode9
 
 import (
 	"context"
+	"dynamodb-with-go/pkg/dynamo"
 	"errors"
 	"time"
 
@@ -13,7 +14,7 @@ import (
 )
 
 type Toggle struct {
-	db    *dynamodb.Client
+	db    dynamo.Client
 	table string
 }
 
@@ -57,7 +58,7 @@ func (s Switch) asLatestItem() switchItem {
 	}
 }
 
-func NewToggle(db *dynamodb.Client, table string) *Toggle {
+func NewToggle(db dynamo.Client, table string) *Toggle {
 	return &Toggle{db: db, table: table}
 }
 
@@ -61,4 +61,20 @@ func TestToggle(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, s.State, true)
 	})
+
+	t.Run("keep switch log and latest switch", func(t *testing.T) {
+		tableName := "ToggleStateTable"
+		db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./template.yml")
+		defer cleanup()
+
+		toggle := NewToggle(db, tableName)
+		createdAt := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+		err := toggle.Save(ctx, Switch{ID: "123", State: true, CreatedAt: createdAt})
+		assert.NoError(t, err)
+
+		err = toggle.Save(ctx, Switch{ID: "123", State: false, CreatedAt: createdAt.Add(10 * time.Second)})
+		assert.NoError(t, err)
+
+		dynamo.AssertTable(t, ctx, db, tableName, "./testdata/save.golden.json")
+	})
 }
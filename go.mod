@@ -12,6 +12,8 @@ require (
 	github.com/awslabs/goformation v1.4.1
 	github.com/davecgh/go-spew v1.1.1
 	github.com/google/uuid v1.1.2
+	github.com/hashicorp/hcl/v2 v2.10.0
+	github.com/sanathkr/yaml v0.0.0-20170819201035-0056894fa522
 	github.com/stretchr/testify v1.5.1
-	golang.org/x/net v0.0.0-20200202094626-16171245cfb2 // indirect
+	github.com/zclconf/go-cty v1.8.0
 )
@@ -0,0 +1,95 @@
+// Package cli holds what commands managing tables from the template share: flags, loading tables,
+// connecting to DynamoDB and reporting drift.
+package cli
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+// Parameters collects -param flags, template parameters given as Name=Value.
+type Parameters map[string]string
+
+func (p Parameters) String() string {
+	return fmt.Sprint(map[string]string(p))
+}
+
+func (p Parameters) Set(v string) error {
+	i := strings.Index(v, "=")
+	if i < 1 {
+		return fmt.Errorf("expected Name=Value, got %q", v)
+	}
+	p[v[:i]] = v[i+1:]
+	return nil
+}
+
+// Table is the table from the template with its logical name.
+type Table struct {
+	Logical string
+	cloudformation.AWSDynamoDBTable
+}
+
+// LoadTables reads tables with the logical names or table names from the template, all of them
+// without names, sorted by logical names.
+func LoadTables(template string, params Parameters, names []string) ([]Table, error) {
+	loaded, err := dynamo.LoadNamedTables(template, names, dynamo.WithParameters(params))
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]Table, 0, len(loaded))
+	for logical, table := range loaded {
+		tables = append(tables, Table{Logical: logical, AWSDynamoDBTable: table})
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Logical < tables[j].Logical })
+	return tables, nil
+}
+
+// AWS is the endpoint, that makes commands talk to AWS with the default credentials and region.
+// It has to be given explicitly, so that tables in the real account aren't changed by accident.
+const AWS = "aws"
+
+// EndpointUsage describes the -endpoint flag.
+const EndpointUsage = "DynamoDB endpoint `url`, or aws for AWS with the default credentials and region (default $" +
+	dynamo.EnvEndpoint + " or http://localhost:8000)"
+
+// Client connects to DynamoDB at the endpoint, or to AWS when the endpoint is AWS. Empty endpoint
+// defaults to DYNAMODB_ENDPOINT or http://localhost:8000, the way dynamo.NewClient does.
+func Client(ctx context.Context, endpoint string) (*dynamodb.Client, error) {
+	switch endpoint {
+	case "":
+		return dynamo.NewClient(ctx)
+	case AWS:
+	default:
+		return dynamo.NewClient(ctx, dynamo.WithEndpoint(endpoint))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not load AWS config: %w", err)
+	}
+	return dynamodb.NewFromConfig(cfg), nil
+}
+
+// ReportDrift writes how the existing table differs from the template to w and returns the differences.
+func ReportDrift(ctx context.Context, w io.Writer, db *dynamodb.Client, table Table) ([]dynamo.Diff, error) {
+	diffs, err := dynamo.DetectDrift(ctx, db, table.AWSDynamoDBTable)
+	if err != nil {
+		return nil, err
+	}
+	if len(diffs) == 0 {
+		fmt.Fprintf(w, "%s (%s): no drift\n", table.Logical, table.TableName)
+		return nil, nil
+	}
+	fmt.Fprintf(w, "%s (%s):\n", table.Logical, table.TableName)
+	for _, d := range diffs {
+		fmt.Fprintf(w, "  %s\n", d)
+	}
+	return diffs, nil
+}
@@ -0,0 +1,83 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"dynamodb-with-go/internal/cli"
+	"dynamodb-with-go/pkg/dynamo"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParameters(t *testing.T) {
+	params := cli.Parameters{}
+	assert.NoError(t, params.Set("Env=prod"))
+	assert.NoError(t, params.Set("Query=a=b"))
+	assert.Error(t, params.Set("=dev"))
+	assert.Error(t, params.Set("Env"))
+	assert.Equal(t, cli.Parameters{"Env": "prod", "Query": "a=b"}, params)
+}
+
+func TestLoadTables(t *testing.T) {
+	tables, err := cli.LoadTables("../../pkg/dynamo/testdata/parameters.yml", cli.Parameters{"Team": "sensors"}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, tables, 2) {
+		assert.Equal(t, "OrdersTable", tables[0].Logical)
+		assert.Equal(t, "TeamTable", tables[1].Logical)
+	}
+
+	tables, err = cli.LoadTables("../../pkg/dynamo/testdata/parameters.yml", nil, []string{"dev-orders"})
+	assert.NoError(t, err)
+	if assert.Len(t, tables, 1) {
+		assert.Equal(t, "OrdersTable", tables[0].Logical)
+	}
+}
+
+func setenv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	setenv(t, dynamo.EnvEndpoint, "http://127.0.0.1:1")
+	_, err := cli.Client(ctx, "")
+	if assert.Error(t, err, "empty endpoint must not fall back to AWS") {
+		assert.Contains(t, err.Error(), "http://127.0.0.1:1")
+	}
+
+	_, err = cli.Client(ctx, dynamo.InMemory)
+	assert.NoError(t, err)
+}
+
+func TestReportDrift(t *testing.T) {
+	ctx := context.Background()
+	db, err := cli.Client(ctx, dynamo.InMemory)
+	assert.NoError(t, err)
+	tables, err := cli.LoadTables("../../pkg/dynamo/testdata/template.yml", nil, []string{"PartitionKeyTable"})
+	assert.NoError(t, err)
+	table := tables[0]
+
+	var out bytes.Buffer
+	diffs, err := cli.ReportDrift(ctx, &out, db, table)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 1)
+	assert.Equal(t, "PartitionKeyTable (PartitionKeyTable):\n  table: want PartitionKeyTable, got none\n", out.String())
+
+	assert.NoError(t, dynamo.CreateTable(ctx, db, table.AWSDynamoDBTable))
+	defer dynamo.DeleteTables(ctx, db, []string{table.TableName})
+	out.Reset()
+	diffs, err = cli.ReportDrift(ctx, &out, db, table)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+	assert.Equal(t, "PartitionKeyTable (PartitionKeyTable): no drift\n", out.String())
+}
@@ -0,0 +1,176 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxBatchGetItems is the number of keys BatchGetItem accepts at once.
+const maxBatchGetItems = 100
+
+// BatchGetInput lists keys of the items BatchGet reads from the table. Projection may leave out
+// key attributes, they are read anyway to match items with the keys.
+type BatchGetInput struct {
+	TableName                string
+	Keys                     []map[string]types.AttributeValue
+	ProjectionExpression     *string
+	ExpressionAttributeNames map[string]string
+	ConsistentRead           bool
+}
+
+// GetResult is the item read for the key. Found is false when the table has no item with the key.
+type GetResult struct {
+	Key   map[string]types.AttributeValue
+	Item  map[string]types.AttributeValue
+	Found bool
+}
+
+// Decode unmarshals the item into out, pointer to the struct or map.
+func (r GetResult) Decode(out interface{}) error {
+	return attributevalue.UnmarshalMap(r.Item, out)
+}
+
+// BatchGet reads items with BatchGetItem and returns results in the order of the keys. Keys are
+// deduplicated and sent in chunks of 100, concurrently (see WithConcurrency). UnprocessedKeys
+// are resubmitted with jittered exponential backoff, the same way BatchWriter resubmits UnprocessedItems.
+func BatchGet(ctx context.Context, db Client, in BatchGetInput, opts ...BatchOption) ([]GetResult, error) {
+	o := newBatchOptions(opts)
+	results := make([]GetResult, len(in.Keys))
+	if len(in.Keys) == 0 {
+		return results, nil
+	}
+
+	names := sortedKeyNames(in.Keys[0])
+	positions := map[string][]int{}
+	var unique []map[string]types.AttributeValue
+	for i, key := range in.Keys {
+		id, err := keyID(key, names)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := positions[id]; !ok {
+			unique = append(unique, key)
+		}
+		positions[id] = append(positions[id], i)
+		results[i].Key = key
+	}
+
+	request := types.KeysAndAttributes{
+		ExpressionAttributeNames: in.ExpressionAttributeNames,
+		ConsistentRead:           aws.Bool(in.ConsistentRead),
+	}
+	if in.ProjectionExpression != nil {
+		request.ExpressionAttributeNames = make(map[string]string, len(in.ExpressionAttributeNames)+len(names))
+		for k, v := range in.ExpressionAttributeNames {
+			request.ExpressionAttributeNames[k] = v
+		}
+		projected := projectedNames(*in.ProjectionExpression, in.ExpressionAttributeNames)
+		projection := []string{*in.ProjectionExpression}
+		for i, name := range names {
+			if projected[name] {
+				continue
+			}
+			alias := "#batchget_key" + strconv.Itoa(i)
+			request.ExpressionAttributeNames[alias] = name
+			projection = append(projection, alias)
+		}
+		request.ProjectionExpression = aws.String(strings.Join(projection, ", "))
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+	)
+	chunks := make(chan []map[string]types.AttributeValue)
+	for i := 0; i < o.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				items, err := batchGet(ctx, db, in.TableName, request, chunk, o.maxAttempts)
+				mu.Lock()
+				if err != nil && first == nil {
+					first = err
+				}
+				for _, item := range items {
+					id, err := keyID(item, names)
+					if err != nil {
+						continue
+					}
+					for _, i := range positions[id] {
+						results[i].Item, results[i].Found = item, true
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for len(unique) > 0 {
+		n := maxBatchGetItems
+		if len(unique) < n {
+			n = len(unique)
+		}
+		chunks <- unique[:n]
+		unique = unique[n:]
+	}
+	close(chunks)
+	wg.Wait()
+
+	if first != nil {
+		return nil, fmt.Errorf("could not get items from table %q: %w", in.TableName, first)
+	}
+	return results, nil
+}
+
+// batchGet reads items with the keys, resubmitting unprocessed keys.
+func batchGet(ctx context.Context, db Client, table string, request types.KeysAndAttributes, keys []map[string]types.AttributeValue, maxAttempts int) ([]map[string]types.AttributeValue, error) {
+	request.Keys = keys
+	pending := map[string]types.KeysAndAttributes{table: request}
+	var items []map[string]types.AttributeValue
+	err := retryBatch(ctx, maxAttempts, func(ctx context.Context) (int, error) {
+		out, err := db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: pending})
+		if err != nil {
+			return 0, err
+		}
+		items = append(items, out.Responses[table]...)
+		pending = out.UnprocessedKeys
+		return len(pending[table].Keys), nil
+	})
+	return items, err
+}
+
+// projectedNames returns names of the top level attributes of the projection. DynamoDB rejects
+// projections with overlapping paths, so key attributes are added only when missing.
+func projectedNames(projection string, aliases map[string]string) map[string]bool {
+	names := map[string]bool{}
+	for _, p := range strings.Split(projection, ",") {
+		name := strings.TrimSpace(p)
+		if i := strings.IndexAny(name, ".["); i >= 0 {
+			name = name[:i]
+		}
+		if alias, ok := aliases[name]; ok {
+			name = alias
+		}
+		names[name] = true
+	}
+	return names
+}
+
+func sortedKeyNames(key map[string]types.AttributeValue) []string {
+	names := make([]string, 0, len(key))
+	for name := range key {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,106 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"dynamodb-with-go/pkg/dynamo/memdb"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchGet(t *testing.T) {
+	ctx := context.Background()
+	mem := memdb.New()
+	db := setupBatchTables(t, ctx, mem)
+
+	w := dynamo.NewBatchWriter(ctx, db)
+	for i := 0; i < 200; i++ {
+		assert.NoError(t, w.Put("CompositePrimaryKeyTable", map[string]types.AttributeValue{
+			"pk":   &types.AttributeValueMemberS{Value: strconv.Itoa(i)},
+			"sk":   &types.AttributeValueMemberS{Value: "a"},
+			"name": &types.AttributeValueMemberS{Value: "item " + strconv.Itoa(i)},
+			"size": &types.AttributeValueMemberN{Value: strconv.Itoa(i)},
+		}))
+	}
+	assert.NoError(t, w.Close())
+	mem.LimitBatch(30)
+
+	key := func(pk string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: pk},
+			"sk": &types.AttributeValueMemberS{Value: "a"},
+		}
+	}
+	var keys []map[string]types.AttributeValue
+	for i := 249; i >= 0; i-- {
+		keys = append(keys, key(strconv.Itoa(i)))
+	}
+	keys = append(keys, key("7"), key("missing"), key("7"))
+
+	t.Run("read items in order of the keys", func(t *testing.T) {
+		results, err := dynamo.BatchGet(ctx, db, dynamo.BatchGetInput{
+			TableName:      "CompositePrimaryKeyTable",
+			Keys:           keys,
+			ConsistentRead: true,
+		}, dynamo.WithConcurrency(2))
+		assert.NoError(t, err)
+		assert.Len(t, results, len(keys))
+		for i, r := range results {
+			assert.Equal(t, keys[i], r.Key)
+			pk := keys[i]["pk"].(*types.AttributeValueMemberS).Value
+			n, err := strconv.Atoi(pk)
+			if err != nil || n >= 200 {
+				assert.False(t, r.Found, pk)
+				assert.Nil(t, r.Item, pk)
+				continue
+			}
+			var item struct {
+				Name string `dynamodbav:"name"`
+				Size int    `dynamodbav:"size"`
+			}
+			assert.True(t, r.Found, pk)
+			assert.NoError(t, r.Decode(&item))
+			assert.Equal(t, "item "+pk, item.Name)
+			assert.Equal(t, n, item.Size)
+		}
+	})
+
+	t.Run("project attributes", func(t *testing.T) {
+		results, err := dynamo.BatchGet(ctx, db, dynamo.BatchGetInput{
+			TableName:                "CompositePrimaryKeyTable",
+			Keys:                     []map[string]types.AttributeValue{key("1"), key("2")},
+			ProjectionExpression:     aws.String("#name"),
+			ExpressionAttributeNames: map[string]string{"#name": "name"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]types.AttributeValue{
+			"pk":   &types.AttributeValueMemberS{Value: "2"},
+			"sk":   &types.AttributeValueMemberS{Value: "a"},
+			"name": &types.AttributeValueMemberS{Value: "item 2"},
+		}, results[1].Item)
+
+		results, err = dynamo.BatchGet(ctx, db, dynamo.BatchGetInput{
+			TableName:            "CompositePrimaryKeyTable",
+			Keys:                 []map[string]types.AttributeValue{key("1")},
+			ProjectionExpression: aws.String("pk, size"),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]types.AttributeValue{
+			"pk":   &types.AttributeValueMemberS{Value: "1"},
+			"sk":   &types.AttributeValueMemberS{Value: "a"},
+			"size": &types.AttributeValueMemberN{Value: "1"},
+		}, results[0].Item)
+	})
+
+	t.Run("reject keys of other shape", func(t *testing.T) {
+		_, err := dynamo.BatchGet(ctx, db, dynamo.BatchGetInput{
+			TableName: "CompositePrimaryKeyTable",
+			Keys:      []map[string]types.AttributeValue{key("1"), {"pk": &types.AttributeValueMemberS{Value: "2"}}},
+		})
+		assert.Error(t, err)
+	})
+}
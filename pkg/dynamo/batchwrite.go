@@ -0,0 +1,446 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"dynamodb-with-go/pkg/dynamo/ddbjson"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// maxBatchWriteItems is the number of items BatchWriteItem accepts at once.
+	maxBatchWriteItems = 25
+	// maxBatchWriteSize is the size of items BatchWriteItem accepts at once.
+	maxBatchWriteSize = 16 << 20
+	// maxItemSize is the size of the biggest item DynamoDB stores.
+	maxItemSize = 400 << 10
+	// maxBatchBackoff caps the backoff between BatchWriteItem or BatchGetItem calls. Throttled tables
+	// may need many seconds to process pending requests, so it's far longer than maxPollInterval.
+	maxBatchBackoff = 20 * time.Second
+	// defaultBatchConcurrency is the number of requests BatchWriter and BatchGet send, and segments
+	// ParallelScan reads, at once, unless set with WithConcurrency or WithScanConcurrency.
+	defaultBatchConcurrency = 4
+)
+
+// ErrUnprocessed is the reason of the failure of requests, that DynamoDB kept returning as unprocessed
+// for the number of attempts set with WithMaxAttempts.
+var ErrUnprocessed = errors.New("request was not processed")
+
+// errWriterClosed is returned when requests are added to the closed BatchWriter.
+var errWriterClosed = errors.New("batch writer is closed")
+
+// WriteFailure is the put or delete request, that could not be written.
+type WriteFailure struct {
+	Table   string
+	Request types.WriteRequest
+	Err     error
+}
+
+// BatchWriteError lists requests, that BatchWriter could not write.
+type BatchWriteError struct {
+	Failures []WriteFailure
+}
+
+func (e *BatchWriteError) Error() string {
+	return fmt.Sprintf("could not write %d items, first to table %q: %v", len(e.Failures), e.Failures[0].Table, e.Failures[0].Err)
+}
+
+// Unwrap returns reason of the first failure.
+func (e *BatchWriteError) Unwrap() error {
+	return e.Failures[0].Err
+}
+
+// BatchWriter writes puts and deletes with BatchWriteItem. Requests are grouped into batches of at most
+// 25 items or 16MB, and written by concurrent workers (see WithConcurrency). UnprocessedItems are
+// resubmitted with jittered exponential backoff until they are written or the context is done,
+// unless the number of attempts is limited with WithMaxAttempts. Requests of one batch never share the key, as
+// DynamoDB rejects such batches, so the key schema of every table is read with DescribeTable.
+//
+// Every item is written by the same worker, chosen by its key, so requests for the same item are written
+// in the order they were added, e.g. the item put and then deleted is gone. Requests for different items
+// are not ordered.
+//
+// Requests are written in the background, Close waits for all of them and reports failed ones.
+// Put and Delete can be called from many goroutines. Once Close is called, they fail.
+type BatchWriter struct {
+	ctx      context.Context
+	db       Client
+	o        batchOptions
+	requests chan writeRequest
+	// batches are queues of the workers.
+	batches []chan []writeRequest
+	wg      sync.WaitGroup
+	// sending is held by Put and Delete while they send the request, so that Close doesn't close requests under them.
+	sending sync.RWMutex
+
+	mu       sync.Mutex
+	closed   bool
+	failures []WriteFailure
+}
+
+type writeRequest struct {
+	table string
+	req   types.WriteRequest
+}
+
+// BatchOption configures NewBatchWriter and BatchGet.
+type BatchOption func(*batchOptions)
+
+type batchOptions struct {
+	concurrency int
+	maxAttempts int
+}
+
+func newBatchOptions(opts []BatchOption) batchOptions {
+	o := batchOptions{concurrency: defaultBatchConcurrency}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.concurrency < 1 {
+		o.concurrency = defaultBatchConcurrency
+	}
+	return o
+}
+
+// WithConcurrency sets how many requests BatchWriter and BatchGet send at once. Default is 4.
+// Requests of BatchWriter for the same item stay in order whatever the concurrency.
+func WithConcurrency(n int) BatchOption {
+	return func(o *batchOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithMaxAttempts makes BatchWriter and BatchGet fail requests with ErrUnprocessed after n calls
+// in a row, that did not process any of them. Default, zero, retries until the context is done,
+// so set the deadline of the context to bound how long throttled requests are retried.
+func WithMaxAttempts(n int) BatchOption {
+	return func(o *batchOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// NewBatchWriter creates BatchWriter and starts its workers. They stop when context is canceled,
+// failing requests that were not written yet.
+func NewBatchWriter(ctx context.Context, db Client, opts ...BatchOption) *BatchWriter {
+	return newBatchWriter(ctx, db, newBatchOptions(opts))
+}
+
+func newBatchWriter(ctx context.Context, db Client, o batchOptions) *BatchWriter {
+	workers := o.concurrency
+	w := &BatchWriter{
+		ctx:      ctx,
+		db:       db,
+		o:        o,
+		requests: make(chan writeRequest, maxBatchWriteItems),
+		batches:  make([]chan []writeRequest, workers),
+	}
+	w.wg.Add(workers)
+	for i := range w.batches {
+		batches := make(chan []writeRequest)
+		w.batches[i] = batches
+		go func() {
+			defer w.wg.Done()
+			for batch := range batches {
+				w.write(batch)
+			}
+		}()
+	}
+	go w.dispatch()
+	return w
+}
+
+// Put adds put request of the item to the table.
+func (w *BatchWriter) Put(table string, item map[string]types.AttributeValue) error {
+	return w.add(writeRequest{table: table, req: types.WriteRequest{PutRequest: &types.PutRequest{Item: item}}})
+}
+
+// Delete adds delete request of the item with the key from the table.
+func (w *BatchWriter) Delete(table string, key map[string]types.AttributeValue) error {
+	return w.add(writeRequest{table: table, req: types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}}})
+}
+
+func (w *BatchWriter) add(r writeRequest) error {
+	w.sending.RLock()
+	defer w.sending.RUnlock()
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+	if closed {
+		return errWriterClosed
+	}
+	select {
+	case w.requests <- r:
+		return nil
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+}
+
+// Close writes remaining requests and waits for the workers. It returns *BatchWriteError
+// when any of the requests failed.
+func (w *BatchWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return errWriterClosed
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	w.sending.Lock()
+	close(w.requests)
+	w.sending.Unlock()
+	w.wg.Wait()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.failures) > 0 {
+		return &BatchWriteError{Failures: w.failures}
+	}
+	return nil
+}
+
+// dispatch groups requests into batches and hands them to the workers. Requests for the item
+// always go to the same worker, that writes its batches one by one.
+func (w *BatchWriter) dispatch() {
+	defer func() {
+		for _, batches := range w.batches {
+			close(batches)
+		}
+	}()
+	schemas := map[string][]string{}
+	type pendingBatch struct {
+		requests []writeRequest
+		size     int
+		ids      map[string]bool
+	}
+	pending := make([]pendingBatch, len(w.batches))
+	flush := func(worker int) {
+		b := &pending[worker]
+		if len(b.requests) == 0 {
+			return
+		}
+		select {
+		case w.batches[worker] <- b.requests:
+		case <-w.ctx.Done():
+			w.fail(b.requests, w.ctx.Err())
+		}
+		*b = pendingBatch{}
+	}
+
+	for r := range w.requests {
+		keys, ok := schemas[r.table]
+		if !ok {
+			var err error
+			if keys, err = describeKeys(w.ctx, w.db, r.table); err != nil {
+				w.fail([]writeRequest{r}, err)
+				continue
+			}
+			schemas[r.table] = keys
+		}
+		id, n, err := r.identify(keys)
+		if err != nil {
+			w.fail([]writeRequest{r}, err)
+			continue
+		}
+		h := fnv.New32a()
+		h.Write([]byte(id))
+		worker := int(h.Sum32() % uint32(len(w.batches)))
+		b := &pending[worker]
+		if len(b.requests) == maxBatchWriteItems || b.size+n > maxBatchWriteSize || b.ids[id] {
+			flush(worker)
+		}
+		if b.ids == nil {
+			b.ids = map[string]bool{}
+		}
+		b.requests = append(b.requests, r)
+		b.size += n
+		b.ids[id] = true
+	}
+	for worker := range pending {
+		flush(worker)
+	}
+}
+
+// identify returns id of the item the request writes, made of the table name and the key, and size of the request.
+func (r writeRequest) identify(keys []string) (string, int, error) {
+	var item map[string]types.AttributeValue
+	if r.req.PutRequest != nil {
+		item = r.req.PutRequest.Item
+	} else if r.req.DeleteRequest != nil {
+		item = r.req.DeleteRequest.Key
+	}
+	id, err := keyID(item, keys)
+	if err != nil {
+		return "", 0, err
+	}
+	n := itemSize(item)
+	if n > maxItemSize {
+		return "", 0, fmt.Errorf("item has %d bytes, more than %d allowed", n, maxItemSize)
+	}
+	return r.table + "|" + id, n, nil
+}
+
+// keyID encodes values of the key attributes into the string identifying the item.
+func keyID(item map[string]types.AttributeValue, names []string) (string, error) {
+	var b strings.Builder
+	for _, name := range names {
+		av, ok := item[name]
+		if !ok {
+			return "", fmt.Errorf("item misses key attribute %q", name)
+		}
+		enc, err := ddbjson.Marshal(av)
+		if err != nil {
+			return "", fmt.Errorf("key attribute %q: %w", name, err)
+		}
+		b.Write(enc)
+		b.WriteByte('|')
+	}
+	return b.String(), nil
+}
+
+// write writes the batch, resubmitting unprocessed requests.
+func (w *BatchWriter) write(batch []writeRequest) {
+	pending := map[string][]types.WriteRequest{}
+	for _, r := range batch {
+		pending[r.table] = append(pending[r.table], r.req)
+	}
+	err := retryBatch(w.ctx, w.o.maxAttempts, func(ctx context.Context) (int, error) {
+		out, err := w.db.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+		if err != nil {
+			return 0, err
+		}
+		pending = out.UnprocessedItems
+		n := 0
+		for _, reqs := range pending {
+			n += len(reqs)
+		}
+		return n, nil
+	})
+	if err != nil {
+		var failed []writeRequest
+		for table, reqs := range pending {
+			for _, req := range reqs {
+				failed = append(failed, writeRequest{table: table, req: req})
+			}
+		}
+		w.fail(failed, err)
+	}
+}
+
+func (w *BatchWriter) fail(requests []writeRequest, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, r := range requests {
+		w.failures = append(w.failures, WriteFailure{Table: r.table, Request: r.req, Err: err})
+	}
+}
+
+// retryBatch calls fn until it reports no pending requests or the context is done, sleeping between
+// calls with jittered exponential backoff. When maxAttempts is positive, it gives up with ErrUnprocessed
+// after maxAttempts calls in a row that did not decrease the number of pending requests.
+func retryBatch(ctx context.Context, maxAttempts int, fn func(ctx context.Context) (int, error)) error {
+	interval := minPollInterval
+	attempts := 0
+	last := -1
+	for {
+		pending, err := fn(ctx)
+		if err != nil {
+			return err
+		}
+		if pending == 0 {
+			return nil
+		}
+		if last >= 0 && pending >= last {
+			attempts++
+		} else {
+			attempts = 1
+		}
+		if maxAttempts > 0 && attempts >= maxAttempts {
+			return ErrUnprocessed
+		}
+		last = pending
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(rand.Int63n(int64(interval))) + 1):
+		}
+		interval *= 2
+		if interval > maxBatchBackoff {
+			interval = maxBatchBackoff
+		}
+	}
+}
+
+// describeKeys returns names of the key attributes of the table.
+func describeKeys(ctx context.Context, db Client, table string) ([]string, error) {
+	out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err != nil {
+		return nil, fmt.Errorf("could not describe table %q: %w", table, err)
+	}
+	var keys []string
+	for _, k := range out.Table.KeySchema {
+		keys = append(keys, aws.ToString(k.AttributeName))
+	}
+	return keys, nil
+}
+
+// itemSize approximates the size of the item the way DynamoDB counts it.
+func itemSize(item map[string]types.AttributeValue) int {
+	n := 0
+	for name, av := range item {
+		n += len(name) + valueSize(av)
+	}
+	return n
+}
+
+func valueSize(av types.AttributeValue) int {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return len(v.Value)
+	case *types.AttributeValueMemberN:
+		return len(strings.TrimLeft(v.Value, "-0"))/2 + 1
+	case *types.AttributeValueMemberB:
+		return len(v.Value)
+	case *types.AttributeValueMemberBOOL, *types.AttributeValueMemberNULL:
+		return 1
+	case *types.AttributeValueMemberL:
+		n := 3
+		for _, e := range v.Value {
+			n += valueSize(e) + 1
+		}
+		return n
+	case *types.AttributeValueMemberM:
+		return 3 + itemSize(v.Value) + len(v.Value)
+	case *types.AttributeValueMemberSS:
+		n := 0
+		for _, e := range v.Value {
+			n += len(e)
+		}
+		return n
+	case *types.AttributeValueMemberNS:
+		n := 0
+		for _, e := range v.Value {
+			n += len(strings.TrimLeft(e, "-0"))/2 + 1
+		}
+		return n
+	case *types.AttributeValueMemberBS:
+		n := 0
+		for _, e := range v.Value {
+			n += len(e)
+		}
+		return n
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,227 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"dynamodb-with-go/pkg/dynamo/memdb"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupBatchTables(t *testing.T, ctx context.Context, mem *memdb.DB) *dynamodb.Client {
+	srv := httptest.NewServer(mem)
+	t.Cleanup(srv.Close)
+	db, err := dynamo.NewClient(ctx, dynamo.WithEndpoint(srv.URL))
+	assert.NoError(t, err)
+	for _, name := range []string{"PartitionKeyTable", "CompositePrimaryKeyTable"} {
+		table, err := dynamo.LoadTable("./testdata/template.yml", name)
+		assert.NoError(t, err)
+		assert.NoError(t, dynamo.CreateTable(ctx, db, table))
+	}
+	return db
+}
+
+func count(t *testing.T, ctx context.Context, db *dynamodb.Client, table string) int {
+	out, err := db.Scan(ctx, &dynamodb.ScanInput{TableName: aws.String(table), Select: types.SelectCount})
+	assert.NoError(t, err)
+	return int(out.Count)
+}
+
+func TestBatchWriter(t *testing.T) {
+	ctx := context.Background()
+	mem := memdb.New()
+	mem.LimitBatch(7)
+	db := setupBatchTables(t, ctx, mem)
+
+	w := dynamo.NewBatchWriter(ctx, db, dynamo.WithConcurrency(3))
+	for i := 0; i < 60; i++ {
+		pk := &types.AttributeValueMemberS{Value: strconv.Itoa(i)}
+		assert.NoError(t, w.Put("PartitionKeyTable", map[string]types.AttributeValue{"pk": pk}))
+		assert.NoError(t, w.Put("CompositePrimaryKeyTable", map[string]types.AttributeValue{
+			"pk": pk,
+			"sk": &types.AttributeValueMemberS{Value: "a"},
+		}))
+	}
+	assert.NoError(t, w.Close())
+	assert.Equal(t, 60, count(t, ctx, db, "PartitionKeyTable"))
+	assert.Equal(t, 60, count(t, ctx, db, "CompositePrimaryKeyTable"))
+
+	w = dynamo.NewBatchWriter(ctx, db)
+	for i := 0; i < 50; i++ {
+		assert.NoError(t, w.Delete("PartitionKeyTable", map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: strconv.Itoa(i)},
+		}))
+	}
+	assert.NoError(t, w.Close())
+	assert.Equal(t, 10, count(t, ctx, db, "PartitionKeyTable"))
+	assert.Error(t, w.Put("PartitionKeyTable", map[string]types.AttributeValue{}))
+}
+
+func TestBatchWriterDuplicateKeys(t *testing.T) {
+	ctx := context.Background()
+	db := setupBatchTables(t, ctx, memdb.New())
+
+	w := dynamo.NewBatchWriter(ctx, db, dynamo.WithConcurrency(1))
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, w.Put("PartitionKeyTable", map[string]types.AttributeValue{
+			"pk":    &types.AttributeValueMemberS{Value: "1"},
+			"count": &types.AttributeValueMemberN{Value: strconv.Itoa(i)},
+		}))
+	}
+	assert.NoError(t, w.Close())
+
+	out, err := db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("PartitionKeyTable"),
+		Key:       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: "1"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "9"}, out.Item["count"])
+}
+
+func TestBatchWriterFailures(t *testing.T) {
+	ctx := context.Background()
+	db := setupBatchTables(t, ctx, memdb.New())
+
+	w := dynamo.NewBatchWriter(ctx, db)
+	assert.NoError(t, w.Put("PartitionKeyTable", map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: "1"}}))
+	assert.NoError(t, w.Put("PartitionKeyTable", map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "2"}}))
+	assert.NoError(t, w.Put("PartitionKeyTable", map[string]types.AttributeValue{
+		"pk":   &types.AttributeValueMemberS{Value: "3"},
+		"blob": &types.AttributeValueMemberS{Value: strings.Repeat("x", 500<<10)},
+	}))
+	assert.NoError(t, w.Delete("MissingTable", map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: "4"}}))
+	err := w.Close()
+
+	var batchErr *dynamo.BatchWriteError
+	if assert.True(t, errors.As(err, &batchErr)) && assert.Len(t, batchErr.Failures, 3) {
+		var reasons []string
+		for _, f := range batchErr.Failures[:2] {
+			reasons = append(reasons, f.Table+": "+f.Err.Error())
+		}
+		assert.ElementsMatch(t, []string{
+			`PartitionKeyTable: item misses key attribute "pk"`,
+			"PartitionKeyTable: item has 512007 bytes, more than 409600 allowed",
+		}, reasons)
+		var notFound *types.ResourceNotFoundException
+		assert.Equal(t, "MissingTable", batchErr.Failures[2].Table)
+		assert.True(t, errors.As(batchErr.Failures[2].Err, &notFound))
+	}
+	assert.Equal(t, 1, count(t, ctx, db, "PartitionKeyTable"))
+}
+
+func TestBatchWriterOrder(t *testing.T) {
+	ctx := context.Background()
+	mem := memdb.New()
+	mem.LimitBatch(3)
+	db := setupBatchTables(t, ctx, mem)
+
+	w := dynamo.NewBatchWriter(ctx, db, dynamo.WithConcurrency(4))
+	for n := 0; n < 3; n++ {
+		for i := 0; i < 40; i++ {
+			assert.NoError(t, w.Put("PartitionKeyTable", map[string]types.AttributeValue{
+				"pk":    &types.AttributeValueMemberS{Value: strconv.Itoa(i)},
+				"count": &types.AttributeValueMemberN{Value: strconv.Itoa(n)},
+			}))
+		}
+	}
+	for i := 0; i < 40; i += 2 {
+		assert.NoError(t, w.Delete("PartitionKeyTable", map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: strconv.Itoa(i)},
+		}))
+	}
+	assert.NoError(t, w.Close())
+
+	out, err := db.Scan(ctx, &dynamodb.ScanInput{TableName: aws.String("PartitionKeyTable")})
+	assert.NoError(t, err)
+	assert.Len(t, out.Items, 20)
+	for _, item := range out.Items {
+		pk, _ := strconv.Atoi(item["pk"].(*types.AttributeValueMemberS).Value)
+		assert.Equal(t, 1, pk%2, "even items are deleted after they are put")
+		assert.Equal(t, &types.AttributeValueMemberN{Value: "2"}, item["count"], "the last put wins")
+	}
+}
+
+func TestBatchWriterConcurrentClose(t *testing.T) {
+	ctx := context.Background()
+	db := setupBatchTables(t, ctx, memdb.New())
+
+	w := dynamo.NewBatchWriter(ctx, db)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				err := w.Put("PartitionKeyTable", map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{Value: strconv.Itoa(g*100 + i)},
+				})
+				if err != nil {
+					return
+				}
+			}
+		}(g)
+	}
+	assert.NoError(t, w.Close())
+	wg.Wait()
+	assert.Error(t, w.Put("PartitionKeyTable", map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: "1"}}))
+	assert.Error(t, w.Close())
+}
+
+// unprocessedClient returns all the items as unprocessed.
+type unprocessedClient struct {
+	*dynamodb.Client
+}
+
+func (c unprocessedClient) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	return &dynamodb.BatchWriteItemOutput{UnprocessedItems: params.RequestItems}, nil
+}
+
+func TestBatchWriterCanceled(t *testing.T) {
+	ctx := context.Background()
+	db := setupBatchTables(t, ctx, memdb.New())
+
+	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	w := dynamo.NewBatchWriter(ctx, unprocessedClient{db})
+	for i := 0; i < 30; i++ {
+		assert.NoError(t, w.Put("PartitionKeyTable", map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: strconv.Itoa(i)},
+		}))
+	}
+	err := w.Close()
+
+	var batchErr *dynamo.BatchWriteError
+	if assert.True(t, errors.As(err, &batchErr)) {
+		assert.Len(t, batchErr.Failures, 30)
+	}
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestBatchWriterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	db := setupBatchTables(t, ctx, memdb.New())
+
+	w := dynamo.NewBatchWriter(ctx, unprocessedClient{db}, dynamo.WithMaxAttempts(3))
+	for i := 0; i < 30; i++ {
+		assert.NoError(t, w.Put("PartitionKeyTable", map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: strconv.Itoa(i)},
+		}))
+	}
+	err := w.Close()
+
+	var batchErr *dynamo.BatchWriteError
+	if assert.True(t, errors.As(err, &batchErr)) {
+		assert.Len(t, batchErr.Failures, 30)
+	}
+	assert.True(t, errors.Is(err, dynamo.ErrUnprocessed))
+}
@@ -0,0 +1,257 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+// Errors collects failures of operations run on many tables at once.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// SchemaMismatchError is returned by EnsureTable when table already exists, but its
+// key schema, attribute definitions or indexes differ from the ones in the template.
+type SchemaMismatchError struct {
+	Table       string
+	Differences []Diff
+}
+
+func (e *SchemaMismatchError) Error() string {
+	msgs := make([]string, 0, len(e.Differences))
+	for _, d := range e.Differences {
+		msgs = append(msgs, d.String())
+	}
+	return fmt.Sprintf("table %q exists with different schema: %s", e.Table, strings.Join(msgs, "; "))
+}
+
+// LoadTables reads all DynamoDB tables from the CloudFormation template file under `path`,
+// either YAML or JSON. AWS::Serverless::SimpleTable resources of SAM templates are translated
+// into AWS::DynamoDB::Table (see FromSimpleTable).
+//
+// Files with .tf extension, their glob patterns (e.g. ./infra/*.tf) and directories are read
+// as Terraform module, with aws_dynamodb_table resources keyed by their names (see FromTerraform).
+//
+// Parameters take default values from the template, unless they are set with WithParameters.
+// Ref, Fn::Sub, Fn::Join, Fn::If, Fn::FindInMap, Fn::Select, Fn::Split and Fn::Base64 in properties
+// of the tables are resolved, tables with false Condition are left out. Pseudo parameters, e.g. AWS::Region
+// or AWS::StackName, have local values. Fn::GetAtt, Fn::ImportValue and Ref to other resources
+// can't be resolved without deploying the stack, they fail only tables using them.
+// Tables are keyed by their logical names. Tables without TableName are named after their logical names.
+func LoadTables(path string, opts ...Option) (map[string]cloudformation.AWSDynamoDBTable, error) {
+	l, err := loadTables(path, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return l.pick()
+}
+
+func loadTables(path string, o options) (loaded, error) {
+	if info, err := os.Stat(path); strings.HasSuffix(path, ".tf") || err == nil && info.IsDir() {
+		return readTerraformTables(path, o)
+	}
+	return readTables(path, o)
+}
+
+// LoadTable reads DynamoDB table from the CloudFormation template file under `path` (see LoadTables).
+// Table is looked up by its logical name and, when there is no such resource, by the table name.
+// Other tables of the template don't need to be resolvable.
+func LoadTable(path, name string, opts ...Option) (cloudformation.AWSDynamoDBTable, error) {
+	tables, err := LoadNamedTables(path, []string{name}, opts...)
+	if err != nil {
+		return cloudformation.AWSDynamoDBTable{}, err
+	}
+	for _, table := range tables {
+		return table, nil
+	}
+	return cloudformation.AWSDynamoDBTable{}, nil
+}
+
+// LoadNamedTables reads DynamoDB tables from the file under `path` once and picks the ones with the names,
+// looked up like in LoadTable. Tables are keyed by their logical names. Without names, it's LoadTables.
+func LoadNamedTables(path string, names []string, opts ...Option) (map[string]cloudformation.AWSDynamoDBTable, error) {
+	l, err := loadTables(path, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return l.pick(names...)
+}
+
+// CreateTable creates the table, waits until it and its global secondary indexes are ACTIVE
+// and applies settings, that CreateTable does not accept (see ApplySettings).
+// Table is deleted when any of these steps fail.
+func CreateTable(ctx context.Context, db *dynamodb.Client, table cloudformation.AWSDynamoDBTable, opts ...Option) error {
+	return createTable(ctx, db, table, newOptions(opts))
+}
+
+func createTable(ctx context.Context, db *dynamodb.Client, table cloudformation.AWSDynamoDBTable, o options) error {
+	input, err := CreateInput(table)
+	if err != nil {
+		return err
+	}
+	_, err = db.CreateTable(ctx, &input)
+	if err != nil {
+		return fmt.Errorf("could not create table %q: %w", table.TableName, err)
+	}
+
+	desc, err := waitUntilActive(ctx, db, table.TableName, o.timeout)
+	if err == nil {
+		err = ApplySettings(ctx, db, table, *desc)
+	}
+	if err != nil {
+		deleteTables(ctx, db, o, table.TableName)
+		return fmt.Errorf("could not setup table %q: %w", table.TableName, err)
+	}
+	return nil
+}
+
+// CreateTables concurrently creates tables the same way CreateTable does. It returns names of
+// the tables that were created, even when creating some of the other tables failed.
+func CreateTables(ctx context.Context, db *dynamodb.Client, tables []cloudformation.AWSDynamoDBTable, opts ...Option) ([]string, error) {
+	return createTables(ctx, db, tables, newOptions(opts))
+}
+
+func createTables(ctx context.Context, db *dynamodb.Client, tables []cloudformation.AWSDynamoDBTable, o options) ([]string, error) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created []string
+		errs    Errors
+	)
+	for _, table := range tables {
+		wg.Add(1)
+		go func(table cloudformation.AWSDynamoDBTable) {
+			defer wg.Done()
+			err := createTable(ctx, db, table, o)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			created = append(created, table.TableName)
+		}(table)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return created, errs
+	}
+	return created, nil
+}
+
+// DeleteTables concurrently deletes tables and waits until they are gone.
+func DeleteTables(ctx context.Context, db *dynamodb.Client, names []string, opts ...Option) error {
+	if errs := deleteTables(ctx, db, newOptions(opts), names...); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func deleteTables(ctx context.Context, db *dynamodb.Client, o options, names ...string) Errors {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs Errors
+	)
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			_, err := db.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(name)})
+			if err == nil {
+				err = waitUntilDeleted(ctx, db, name, o.timeout)
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("could not delete table %q: %w", name, err))
+				mu.Unlock()
+			}
+		}(name)
+	}
+	wg.Wait()
+	return errs
+}
+
+// EnsureTable creates the table unless it already exists. Existing table is left untouched
+// when it has the same schema as the one in the template, otherwise SchemaMismatchError is returned.
+func EnsureTable(ctx context.Context, db *dynamodb.Client, table cloudformation.AWSDynamoDBTable, opts ...Option) error {
+	return ensureTable(ctx, db, table, newOptions(opts))
+}
+
+func ensureTable(ctx context.Context, db *dynamodb.Client, table cloudformation.AWSDynamoDBTable, o options) error {
+	input, err := CreateInput(table)
+	if err != nil {
+		return err
+	}
+
+	out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: input.TableName})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		err = createTable(ctx, db, table, o)
+		var inUse *types.ResourceInUseException
+		if !errors.As(err, &inUse) {
+			return err
+		}
+		// Somebody else created the table in the meantime.
+		out, err = db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: input.TableName})
+	}
+	if err != nil {
+		return err
+	}
+
+	if diffs := schemaDifferences(input, *out.Table); len(diffs) > 0 {
+		return &SchemaMismatchError{Table: table.TableName, Differences: diffs}
+	}
+	_, err = waitUntilActive(ctx, db, table.TableName, o.timeout)
+	return err
+}
+
+// EnsureTables makes sure that all tables from the CloudFormation template file under `path` exist.
+// It is safe to call it many times, e.g. every time development environment starts.
+func EnsureTables(ctx context.Context, db *dynamodb.Client, path string, opts ...Option) error {
+	o := newOptions(opts)
+	tables, err := LoadTables(path, opts...)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs Errors
+	)
+	for _, table := range tables {
+		wg.Add(1)
+		go func(table cloudformation.AWSDynamoDBTable) {
+			defer wg.Done()
+			if err := ensureTable(ctx, db, table, o); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(table)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"errors"
+	"testing"
+
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadTables(t *testing.T) {
+	tables, err := dynamo.LoadTables("./testdata/bootstrap.yml")
+	assert.NoError(t, err)
+	assert.Len(t, tables, 2)
+	assert.Equal(t, "UsersTable", tables["UsersTable"].TableName)
+	assert.Equal(t, "BootstrapOrdersTable", tables["OrdersTable"].TableName)
+
+	table, err := dynamo.LoadTable("./testdata/bootstrap.yml", "BootstrapOrdersTable")
+	assert.NoError(t, err)
+	assert.Equal(t, tables["OrdersTable"], table)
+
+	_, err = dynamo.LoadTable("./testdata/bootstrap.yml", "MissingTable")
+	assert.EqualError(t, err, `table "MissingTable" not found in ./testdata/bootstrap.yml`)
+}
+
+func TestEnsureTables(t *testing.T) {
+	ctx := context.Background()
+	db := dynamo.LocalClient(t)
+	defer dynamo.DeleteTables(ctx, db, []string{"UsersTable", "BootstrapOrdersTable"})
+
+	err := dynamo.EnsureTables(ctx, db, "./testdata/bootstrap.yml")
+	assert.NoError(t, err)
+
+	err = dynamo.EnsureTables(ctx, db, "./testdata/bootstrap.yml")
+	assert.NoError(t, err)
+
+	users, err := dynamo.LoadTable("./testdata/bootstrap.yml", "UsersTable")
+	assert.NoError(t, err)
+	users.AttributeDefinitions = append(users.AttributeDefinitions, cloudformation.AWSDynamoDBTable_AttributeDefinition{
+		AttributeName: "sk", AttributeType: "S",
+	})
+	users.KeySchema = append(users.KeySchema, cloudformation.AWSDynamoDBTable_KeySchema{
+		AttributeName: "sk", KeyType: "RANGE",
+	})
+
+	err = dynamo.EnsureTable(ctx, db, users)
+	var mismatch *dynamo.SchemaMismatchError
+	if assert.True(t, errors.As(err, &mismatch)) {
+		assert.Equal(t, "UsersTable", mismatch.Table)
+		assert.Equal(t, []dynamo.Diff{
+			{Kind: dynamo.DiffKeySchema, Want: "[pk HASH, sk RANGE]", Got: "[pk HASH]"},
+			{Kind: dynamo.DiffAttributeDefinitions, Want: "[pk S, sk S]", Got: "[pk S]"},
+		}, mismatch.Differences)
+		assert.EqualError(t, err, `table "UsersTable" exists with different schema: `+
+			`key schema: want [pk HASH, sk RANGE], got [pk HASH]; attribute definitions: want [pk S, sk S], got [pk S]`)
+	}
+}
@@ -0,0 +1,77 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// EndpointResolver points DynamoDB client at the URL. When URL is empty,
+// DynamoDB local at http://localhost:8000 is used.
+type EndpointResolver struct {
+	URL string
+}
+
+func (e EndpointResolver) ResolveEndpoint(region string, options dynamodb.EndpointResolverOptions) (aws.Endpoint, error) {
+	if e.URL == "" {
+		return aws.Endpoint{URL: defaultEndpoint, SigningRegion: region}, nil
+	}
+	return aws.Endpoint{URL: e.URL, SigningRegion: region}, nil
+}
+
+// NewClient creates DynamoDB client configured with options and environment variables
+// (see WithEndpoint, WithRegion, WithCredentials and WithConnectTimeout) and checks that
+// DynamoDB can be reached.
+func NewClient(ctx context.Context, opts ...Option) (*dynamodb.Client, error) {
+	o := newOptions(opts)
+	if o.err != nil {
+		return nil, o.err
+	}
+	return newClient(ctx, o)
+}
+
+func newClient(ctx context.Context, o options) (*dynamodb.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(o.region),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(o.accessKeyID, o.secretAccessKey, "")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not setup db connection: %w", err)
+	}
+
+	endpoint := o.endpoint
+	if endpoint == InMemory {
+		if endpoint, err = memoryEndpoint(); err != nil {
+			return nil, err
+		}
+	}
+	db := dynamodb.NewFromConfig(cfg, dynamodb.WithEndpointResolver(EndpointResolver{URL: endpoint}))
+
+	ctx, cancel := context.WithTimeout(ctx, o.connectTimeout)
+	defer cancel()
+	_, err = db.ListTables(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("make sure DynamoDB runs at %s: %w", o.endpoint, err)
+	}
+	return db, nil
+}
+
+// Client is the part of the DynamoDB API the episodes use. *dynamodb.Client implements it, and so can
+// fakes, fault injectors or instrumented clients wrapping it.
+type Client interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+	DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+var _ Client = (*dynamodb.Client)(nil)
@@ -0,0 +1,86 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setenv sets the environment variable until the test ends, restoring the value it had before,
+// e.g. DYNAMODB_ENDPOINT the tests run with.
+func setenv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestNewClient(t *testing.T) {
+	var authorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		body := []byte(`{"TableNames":[]}`)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.Header().Set("X-Amz-Crc32", strconv.FormatUint(uint64(crc32.ChecksumIEEE(body)), 10))
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	t.Run("options", func(t *testing.T) {
+		_, err := dynamo.NewClient(context.Background(),
+			dynamo.WithEndpoint(srv.URL),
+			dynamo.WithRegion("eu-central-1"),
+			dynamo.WithCredentials("key", "secret"),
+		)
+		assert.NoError(t, err)
+		assert.Contains(t, authorization, "Credential=key/")
+		assert.Contains(t, authorization, "/eu-central-1/dynamodb/")
+	})
+
+	t.Run("environment variables", func(t *testing.T) {
+		setenv(t, dynamo.EnvEndpoint, srv.URL)
+		setenv(t, dynamo.EnvRegion, "us-east-1")
+
+		_, err := dynamo.NewClient(context.Background())
+		assert.NoError(t, err)
+		assert.Contains(t, authorization, "Credential=local/")
+		assert.Contains(t, authorization, "/us-east-1/dynamodb/")
+
+		_, err = dynamo.NewClient(context.Background(), dynamo.WithRegion("local"))
+		assert.NoError(t, err)
+		assert.Contains(t, authorization, "/local/dynamodb/")
+	})
+
+	t.Run("invalid connect timeout", func(t *testing.T) {
+		setenv(t, dynamo.EnvConnectTimeout, "soon")
+
+		_, err := dynamo.NewClient(context.Background(), dynamo.WithEndpoint(srv.URL))
+		assert.Error(t, err)
+	})
+
+	t.Run("unreachable endpoint", func(t *testing.T) {
+		unreachable := httptest.NewServer(http.NotFoundHandler())
+		unreachable.Close()
+
+		start := time.Now()
+		_, err := dynamo.NewClient(context.Background(),
+			dynamo.WithEndpoint(unreachable.URL),
+			dynamo.WithConnectTimeout(200*time.Millisecond),
+		)
+		assert.Error(t, err)
+		assert.True(t, time.Since(start) < time.Second)
+	})
+}
@@ -0,0 +1,179 @@
+package dynamo
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"dynamodb-with-go/pkg/dynamo/ddbjson"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const minCursorSecretLength = 16
+
+// Reasons of CursorError.
+var (
+	ErrCursorTampered = errors.New("cursor is malformed or was tampered with")
+	ErrCursorMismatch = errors.New("cursor belongs to a different query")
+)
+
+// CursorError is returned when the cursor cannot be used for the query. Err is ErrCursorTampered
+// or ErrCursorMismatch, so errors.Is tells them apart.
+type CursorError struct {
+	Err error
+}
+
+func (e *CursorError) Error() string {
+	return "invalid cursor: " + e.Err.Error()
+}
+
+func (e *CursorError) Unwrap() error {
+	return e.Err
+}
+
+// CursorCodec turns LastEvaluatedKey into a cursor, that clients of the API carry between requests,
+// and the cursor back into ExclusiveStartKey. Cursors are signed with HMAC-SHA256, and bound to
+// the query with its scope, e.g. QueryScope of the input. Cursors are not encrypted, clients
+// should treat them as opaque, but could decode the key.
+type CursorCodec struct {
+	secret []byte
+}
+
+// NewCursorCodec creates CursorCodec signing cursors with the secret, at least 16 bytes long.
+// All instances of the service have to share the secret, and changing it invalidates issued cursors.
+func NewCursorCodec(secret []byte) (*CursorCodec, error) {
+	if len(secret) < minCursorSecretLength {
+		return nil, fmt.Errorf("cursor secret must have at least %d bytes, got %d", minCursorSecretLength, len(secret))
+	}
+	return &CursorCodec{secret: append([]byte(nil), secret...)}, nil
+}
+
+// cursor is signed payload of the cursor.
+type cursor struct {
+	Scope []byte       `json:"s"`
+	Key   ddbjson.Item `json:"k"`
+}
+
+// Encode returns cursor pointing at the key within the scope. Empty key, returned with the last page,
+// gives empty cursor.
+func (c *CursorCodec) Encode(scope string, key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+	payload, err := json.Marshal(cursor{Scope: scopeHash(scope), Key: key})
+	if err != nil {
+		return "", fmt.Errorf("could not encode cursor: %w", err)
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(c.sign(payload)), nil
+}
+
+// Decode verifies the cursor and returns the key it points at. Empty cursor gives nil key,
+// so the query starts from the beginning. Returns *CursorError when the cursor was changed
+// or was issued for a different scope.
+func (c *CursorCodec) Decode(scope, s string) (map[string]types.AttributeValue, error) {
+	if s == "" {
+		return nil, nil
+	}
+	tampered := &CursorError{Err: ErrCursorTampered}
+	parts := strings.Split(s, ".")
+	if len(parts) != 2 {
+		return nil, tampered
+	}
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return nil, tampered
+	}
+	sig, err := enc.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sig, c.sign(payload)) {
+		return nil, tampered
+	}
+	var cur cursor
+	if err := json.Unmarshal(payload, &cur); err != nil || len(cur.Key) == 0 {
+		return nil, tampered
+	}
+	if !bytes.Equal(cur.Scope, scopeHash(scope)) {
+		return nil, &CursorError{Err: ErrCursorMismatch}
+	}
+	return cur.Key, nil
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func scopeHash(scope string) []byte {
+	sum := sha256.Sum256([]byte(scope))
+	return sum[:16]
+}
+
+// queryScope lists parts of the input deciding which items are read and in what order.
+// Limit and ExclusiveStartKey are left out, as they change from page to page.
+type queryScope struct {
+	Operation     string
+	Table         string
+	Index         string
+	KeyCondition  string `json:",omitempty"`
+	Filter        string `json:",omitempty"`
+	Projection    string `json:",omitempty"`
+	Names         map[string]string
+	Values        ddbjson.Item
+	Select        types.Select `json:",omitempty"`
+	Backward      bool         `json:",omitempty"`
+	Segment       int32        `json:",omitempty"`
+	TotalSegments int32        `json:",omitempty"`
+}
+
+// QueryScope identifies the query for CursorCodec, so its cursors cannot be used with other queries,
+// e.g. with a different partition key value.
+func QueryScope(in *dynamodb.QueryInput) string {
+	return scope(queryScope{
+		Operation:    "Query",
+		Table:        aws.ToString(in.TableName),
+		Index:        aws.ToString(in.IndexName),
+		KeyCondition: aws.ToString(in.KeyConditionExpression),
+		Filter:       aws.ToString(in.FilterExpression),
+		Projection:   aws.ToString(in.ProjectionExpression),
+		Names:        in.ExpressionAttributeNames,
+		Values:       in.ExpressionAttributeValues,
+		Select:       in.Select,
+		Backward:     in.ScanIndexForward != nil && !*in.ScanIndexForward,
+	})
+}
+
+// ScanScope identifies the scan for CursorCodec, the same way QueryScope does for the query.
+func ScanScope(in *dynamodb.ScanInput) string {
+	return scope(queryScope{
+		Operation:     "Scan",
+		Table:         aws.ToString(in.TableName),
+		Index:         aws.ToString(in.IndexName),
+		Filter:        aws.ToString(in.FilterExpression),
+		Projection:    aws.ToString(in.ProjectionExpression),
+		Names:         in.ExpressionAttributeNames,
+		Values:        in.ExpressionAttributeValues,
+		Select:        in.Select,
+		Segment:       aws.ToInt32(in.Segment),
+		TotalSegments: aws.ToInt32(in.TotalSegments),
+	})
+}
+
+func scope(s queryScope) string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		// Values that cannot be encoded fail the request anyway, the scope only has to be stable.
+		s.Values = nil
+		b, _ = json.Marshal(s)
+	}
+	return string(b)
+}
@@ -0,0 +1,120 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var cursorSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestCursorCodec(t *testing.T) {
+	codec, err := dynamo.NewCursorCodec(cursorSecret)
+	assert.NoError(t, err)
+	key := map[string]types.AttributeValue{
+		"pk":   &types.AttributeValueMemberS{Value: "CITY#Poznań"},
+		"sk":   &types.AttributeValueMemberN{Value: "42"},
+		"blob": &types.AttributeValueMemberB{Value: []byte{0, 1, 2}},
+	}
+	scope := dynamo.QueryScope(queryInput("Table"))
+
+	cursor, err := codec.Encode(scope, key)
+	assert.NoError(t, err)
+	assert.NotContains(t, cursor, "Poznań")
+	decoded, err := codec.Decode(scope, cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, key, decoded)
+
+	t.Run("empty key", func(t *testing.T) {
+		cursor, err := codec.Encode(scope, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "", cursor)
+		key, err := codec.Decode(scope, "")
+		assert.NoError(t, err)
+		assert.Nil(t, key)
+	})
+
+	t.Run("reject tampered cursor", func(t *testing.T) {
+		other, err := dynamo.NewCursorCodec([]byte("fedcba9876543210fedcba9876543210"))
+		assert.NoError(t, err)
+		signedByOther, err := other.Encode(scope, key)
+		assert.NoError(t, err)
+		parts := strings.Split(cursor, ".")
+		flipped := []byte(parts[0])
+		flipped[3] ^= 1
+
+		for _, tampered := range []string{
+			string(flipped) + "." + parts[1],
+			parts[0] + "." + parts[1][1:],
+			parts[0],
+			cursor + ".x",
+			"not a cursor",
+			signedByOther,
+		} {
+			_, err := codec.Decode(scope, tampered)
+			var cursorErr *dynamo.CursorError
+			assert.True(t, errors.As(err, &cursorErr), tampered)
+			assert.True(t, errors.Is(err, dynamo.ErrCursorTampered), tampered)
+		}
+	})
+
+	t.Run("reject cursor of other query", func(t *testing.T) {
+		in := queryInput("Table")
+		in.ExpressionAttributeValues[":pk"] = &types.AttributeValueMemberS{Value: "2"}
+		_, err := codec.Decode(dynamo.QueryScope(in), cursor)
+		assert.True(t, errors.Is(err, dynamo.ErrCursorMismatch))
+
+		in = queryInput("Table")
+		in.ScanIndexForward = aws.Bool(false)
+		_, err = codec.Decode(dynamo.QueryScope(in), cursor)
+		assert.True(t, errors.Is(err, dynamo.ErrCursorMismatch))
+	})
+
+	t.Run("accept cursor with other page size", func(t *testing.T) {
+		in := queryInput("Table")
+		in.Limit = aws.Int32(100)
+		in.ExclusiveStartKey = key
+		_, err := codec.Decode(dynamo.QueryScope(in), cursor)
+		assert.NoError(t, err)
+	})
+}
+
+func TestCursorSecret(t *testing.T) {
+	_, err := dynamo.NewCursorCodec([]byte("short"))
+	assert.Error(t, err)
+}
+
+func TestCursorPages(t *testing.T) {
+	ctx := context.Background()
+	db, table, cleanup := setupPages(t, ctx)
+	defer cleanup()
+	codec, err := dynamo.NewCursorCodec(cursorSecret)
+	assert.NoError(t, err)
+
+	var sks []string
+	cursor := ""
+	for requests := 0; requests < 10; requests++ {
+		in := queryInput(table)
+		in.ExclusiveStartKey, err = codec.Decode(dynamo.QueryScope(in), cursor)
+		assert.NoError(t, err)
+		p := dynamo.NewQueryPaginator(db, in, dynamo.WithLimit(10))
+		var items []pageItem
+		assert.NoError(t, p.All(ctx, &items))
+		for _, item := range items {
+			sks = append(sks, item.SK)
+		}
+		cursor, err = codec.Encode(dynamo.QueryScope(in), p.LastEvaluatedKey())
+		assert.NoError(t, err)
+		if cursor == "" {
+			break
+		}
+	}
+	assert.Len(t, sks, 25)
+	assert.Equal(t, "24", sks[24])
+}
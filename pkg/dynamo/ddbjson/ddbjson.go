@@ -0,0 +1,212 @@
+// Package ddbjson encodes and decodes attribute values in the DynamoDB JSON format,
+// the one used by DynamoDB API and AWS CLI, e.g. {"pk": {"S": "1234"}, "count": {"N": "3"}}.
+package ddbjson
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Item is an item in the DynamoDB JSON format.
+type Item map[string]types.AttributeValue
+
+// MarshalJSON encodes item with attributes sorted by name.
+func (i Item) MarshalJSON() ([]byte, error) {
+	out := make(map[string]interface{}, len(i))
+	for name, av := range i {
+		v, err := encode(av)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", name, err)
+		}
+		out[name] = v
+	}
+	return json.Marshal(out)
+}
+
+func (i *Item) UnmarshalJSON(b []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*i = nil
+		return nil
+	}
+	item := make(Item, len(raw))
+	for name, r := range raw {
+		av, err := Unmarshal(r)
+		if err != nil {
+			return fmt.Errorf("attribute %q: %w", name, err)
+		}
+		item[name] = av
+	}
+	*i = item
+	return nil
+}
+
+// Value is a single attribute value in the DynamoDB JSON format.
+type Value struct {
+	types.AttributeValue
+}
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	return Marshal(v.AttributeValue)
+}
+
+func (v *Value) UnmarshalJSON(b []byte) error {
+	av, err := Unmarshal(b)
+	if err != nil {
+		return err
+	}
+	v.AttributeValue = av
+	return nil
+}
+
+// MarshalItem encodes the item.
+func MarshalItem(item map[string]types.AttributeValue) ([]byte, error) {
+	return Item(item).MarshalJSON()
+}
+
+// UnmarshalItem decodes the item.
+func UnmarshalItem(b []byte) (map[string]types.AttributeValue, error) {
+	var item Item
+	if err := item.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Marshal encodes single attribute value.
+func Marshal(av types.AttributeValue) ([]byte, error) {
+	v, err := encode(av)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func encode(av types.AttributeValue) (interface{}, error) {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return map[string]string{"S": v.Value}, nil
+	case *types.AttributeValueMemberN:
+		return map[string]string{"N": v.Value}, nil
+	case *types.AttributeValueMemberB:
+		return map[string][]byte{"B": v.Value}, nil
+	case *types.AttributeValueMemberBOOL:
+		return map[string]bool{"BOOL": v.Value}, nil
+	case *types.AttributeValueMemberNULL:
+		return map[string]bool{"NULL": v.Value}, nil
+	case *types.AttributeValueMemberSS:
+		return map[string][]string{"SS": v.Value}, nil
+	case *types.AttributeValueMemberNS:
+		return map[string][]string{"NS": v.Value}, nil
+	case *types.AttributeValueMemberBS:
+		return map[string][][]byte{"BS": v.Value}, nil
+	case *types.AttributeValueMemberL:
+		list := make([]interface{}, 0, len(v.Value))
+		for i, elem := range v.Value {
+			e, err := encode(elem)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			list = append(list, e)
+		}
+		return map[string]interface{}{"L": list}, nil
+	case *types.AttributeValueMemberM:
+		m := make(map[string]interface{}, len(v.Value))
+		for name, elem := range v.Value {
+			e, err := encode(elem)
+			if err != nil {
+				return nil, fmt.Errorf("attribute %q: %w", name, err)
+			}
+			m[name] = e
+		}
+		return map[string]interface{}{"M": m}, nil
+	default:
+		return nil, fmt.Errorf("unsupported attribute value %T", av)
+	}
+}
+
+// Unmarshal decodes single attribute value.
+func Unmarshal(b []byte) (types.AttributeValue, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	if len(raw) != 1 {
+		return nil, fmt.Errorf("attribute value must have exactly one type descriptor, got %d", len(raw))
+	}
+	for typ, r := range raw {
+		return decode(typ, r)
+	}
+	panic("unreachable")
+}
+
+func decode(typ string, r json.RawMessage) (types.AttributeValue, error) {
+	switch typ {
+	case "S":
+		var s string
+		err := json.Unmarshal(r, &s)
+		return &types.AttributeValueMemberS{Value: s}, err
+	case "N":
+		var n string
+		err := json.Unmarshal(r, &n)
+		return &types.AttributeValueMemberN{Value: n}, err
+	case "B":
+		var s string
+		if err := json.Unmarshal(r, &s); err != nil {
+			return nil, err
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		return &types.AttributeValueMemberB{Value: b}, err
+	case "BOOL":
+		var b bool
+		err := json.Unmarshal(r, &b)
+		return &types.AttributeValueMemberBOOL{Value: b}, err
+	case "NULL":
+		var b bool
+		err := json.Unmarshal(r, &b)
+		return &types.AttributeValueMemberNULL{Value: b}, err
+	case "SS":
+		var ss []string
+		err := json.Unmarshal(r, &ss)
+		return &types.AttributeValueMemberSS{Value: ss}, err
+	case "NS":
+		var ns []string
+		err := json.Unmarshal(r, &ns)
+		return &types.AttributeValueMemberNS{Value: ns}, err
+	case "BS":
+		var bs [][]byte
+		err := json.Unmarshal(r, &bs)
+		return &types.AttributeValueMemberBS{Value: bs}, err
+	case "L":
+		var raw []json.RawMessage
+		if err := json.Unmarshal(r, &raw); err != nil {
+			return nil, err
+		}
+		list := make([]types.AttributeValue, 0, len(raw))
+		for i, elem := range raw {
+			av, err := Unmarshal(elem)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			list = append(list, av)
+		}
+		return &types.AttributeValueMemberL{Value: list}, nil
+	case "M":
+		var m Item
+		if err := m.UnmarshalJSON(r); err != nil {
+			return nil, err
+		}
+		if m == nil {
+			m = Item{}
+		}
+		return &types.AttributeValueMemberM{Value: m}, nil
+	default:
+		return nil, fmt.Errorf("unknown attribute type %q", typ)
+	}
+}
@@ -0,0 +1,59 @@
+package ddbjson_test
+
+import (
+	"dynamodb-with-go/pkg/dynamo/ddbjson"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItem(t *testing.T) {
+	item := map[string]types.AttributeValue{
+		"pk":    &types.AttributeValueMemberS{Value: "SENSOR#1"},
+		"count": &types.AttributeValueMemberN{Value: "3"},
+		"blob":  &types.AttributeValueMemberB{Value: []byte("hello")},
+		"ok":    &types.AttributeValueMemberBOOL{Value: true},
+		"none":  &types.AttributeValueMemberNULL{Value: true},
+		"tags":  &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+		"nums":  &types.AttributeValueMemberNS{Value: []string{"1", "2.5"}},
+		"bins":  &types.AttributeValueMemberBS{Value: [][]byte{[]byte("x")}},
+		"list": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberS{Value: "a"},
+			&types.AttributeValueMemberN{Value: "1"},
+		}},
+		"map": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"nested": &types.AttributeValueMemberS{Value: "b"},
+		}},
+	}
+
+	b, err := ddbjson.MarshalItem(item)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"pk": {"S": "SENSOR#1"},
+		"count": {"N": "3"},
+		"blob": {"B": "aGVsbG8="},
+		"ok": {"BOOL": true},
+		"none": {"NULL": true},
+		"tags": {"SS": ["a", "b"]},
+		"nums": {"NS": ["1", "2.5"]},
+		"bins": {"BS": ["eA=="]},
+		"list": {"L": [{"S": "a"}, {"N": "1"}]},
+		"map": {"M": {"nested": {"S": "b"}}}
+	}`, string(b))
+
+	decoded, err := ddbjson.UnmarshalItem(b)
+	assert.NoError(t, err)
+	assert.Equal(t, item, decoded)
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	_, err := ddbjson.Unmarshal([]byte(`{"S": "a", "N": "1"}`))
+	assert.Error(t, err)
+
+	_, err = ddbjson.Unmarshal([]byte(`{"X": "a"}`))
+	assert.EqualError(t, err, `unknown attribute type "X"`)
+
+	_, err = ddbjson.UnmarshalItem([]byte(`{"pk": {"S": 1}}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,195 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+// FromDescriptionToCloudFormation is the reverse of FromCloudFormationToCreateInput. It transforms
+// description of the existing table into DynamoDB table from CloudFormation template.
+//
+// Time to live, point in time recovery and tags aren't part of the description, use DescribeCloudFormation
+// to get them as well.
+func FromDescriptionToCloudFormation(desc types.TableDescription) cloudformation.AWSDynamoDBTable {
+	t := cloudformation.AWSDynamoDBTable{
+		TableName: aws.ToString(desc.TableName),
+		KeySchema: keySchemaFromDescription(desc.KeySchema),
+	}
+	for _, attr := range desc.AttributeDefinitions {
+		t.AttributeDefinitions = append(t.AttributeDefinitions, cloudformation.AWSDynamoDBTable_AttributeDefinition{
+			AttributeName: aws.ToString(attr.AttributeName),
+			AttributeType: string(attr.AttributeType),
+		})
+	}
+
+	billingMode := billingModeFromDescription(desc)
+	t.BillingMode = string(billingMode)
+	for _, idx := range desc.LocalSecondaryIndexes {
+		t.LocalSecondaryIndexes = append(t.LocalSecondaryIndexes, cloudformation.AWSDynamoDBTable_LocalSecondaryIndex{
+			IndexName:  aws.ToString(idx.IndexName),
+			KeySchema:  keySchemaFromDescription(idx.KeySchema),
+			Projection: projectionFromDescription(idx.Projection),
+		})
+	}
+	for _, idx := range desc.GlobalSecondaryIndexes {
+		gsi := cloudformation.AWSDynamoDBTable_GlobalSecondaryIndex{
+			IndexName:  aws.ToString(idx.IndexName),
+			KeySchema:  keySchemaFromDescription(idx.KeySchema),
+			Projection: projectionFromDescription(idx.Projection),
+		}
+		if billingMode == types.BillingModeProvisioned {
+			gsi.ProvisionedThroughput = throughputFromDescription(idx.ProvisionedThroughput)
+		}
+		t.GlobalSecondaryIndexes = append(t.GlobalSecondaryIndexes, gsi)
+	}
+	if billingMode == types.BillingModeProvisioned {
+		t.ProvisionedThroughput = throughputFromDescription(desc.ProvisionedThroughput)
+	}
+	if s := desc.StreamSpecification; s != nil && aws.ToBool(s.StreamEnabled) {
+		t.StreamSpecification = &cloudformation.AWSDynamoDBTable_StreamSpecification{
+			StreamViewType: string(s.StreamViewType),
+		}
+	}
+	if sse := desc.SSEDescription; sse != nil && (sse.Status == types.SSEStatusEnabled || sse.Status == types.SSEStatusEnabling) {
+		t.SSESpecification = &cloudformation.AWSDynamoDBTable_SSESpecification{SSEEnabled: true}
+	}
+	return t
+}
+
+// billingModeFromDescription defaults to PROVISIONED, as tables created before on-demand
+// capacity existed don't have billing mode summary.
+func billingModeFromDescription(desc types.TableDescription) types.BillingMode {
+	if desc.BillingModeSummary != nil && desc.BillingModeSummary.BillingMode != "" {
+		return desc.BillingModeSummary.BillingMode
+	}
+	return types.BillingModeProvisioned
+}
+
+func throughputFromDescription(p *types.ProvisionedThroughputDescription) *cloudformation.AWSDynamoDBTable_ProvisionedThroughput {
+	if p == nil {
+		return nil
+	}
+	return &cloudformation.AWSDynamoDBTable_ProvisionedThroughput{
+		ReadCapacityUnits:  aws.ToInt64(p.ReadCapacityUnits),
+		WriteCapacityUnits: aws.ToInt64(p.WriteCapacityUnits),
+	}
+}
+
+func keySchemaFromDescription(keys []types.KeySchemaElement) []cloudformation.AWSDynamoDBTable_KeySchema {
+	var schema []cloudformation.AWSDynamoDBTable_KeySchema
+	for _, key := range keys {
+		schema = append(schema, cloudformation.AWSDynamoDBTable_KeySchema{
+			AttributeName: aws.ToString(key.AttributeName),
+			KeyType:       string(key.KeyType),
+		})
+	}
+	return schema
+}
+
+func projectionFromDescription(p *types.Projection) *cloudformation.AWSDynamoDBTable_Projection {
+	if p == nil {
+		return nil
+	}
+	ret := &cloudformation.AWSDynamoDBTable_Projection{ProjectionType: string(p.ProjectionType)}
+	if len(p.NonKeyAttributes) > 0 {
+		ret.NonKeyAttributes = append([]string{}, p.NonKeyAttributes...)
+	}
+	return ret
+}
+
+// DescribeCloudFormation describes existing table, together with its time to live, point in time
+// recovery and tags, as DynamoDB table from CloudFormation template. Use it to bring tables
+// created by hand under infrastructure-as-code, see also Template. Tags are sorted by keys,
+// as DynamoDB lists them in no particular order.
+func DescribeCloudFormation(ctx context.Context, db *dynamodb.Client, tableName string) (cloudformation.AWSDynamoDBTable, error) {
+	out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+	if err != nil {
+		return cloudformation.AWSDynamoDBTable{}, fmt.Errorf("could not describe table %q: %w", tableName, err)
+	}
+	t := FromDescriptionToCloudFormation(*out.Table)
+
+	ttl, err := db.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String(tableName)})
+	if err != nil {
+		return t, fmt.Errorf("could not describe time to live of table %q: %w", tableName, err)
+	}
+	if d := ttl.TimeToLiveDescription; d != nil && (d.TimeToLiveStatus == types.TimeToLiveStatusEnabled || d.TimeToLiveStatus == types.TimeToLiveStatusEnabling) {
+		t.TimeToLiveSpecification = &cloudformation.AWSDynamoDBTable_TimeToLiveSpecification{
+			AttributeName: aws.ToString(d.AttributeName),
+			Enabled:       true,
+		}
+	}
+
+	backups, err := db.DescribeContinuousBackups(ctx, &dynamodb.DescribeContinuousBackupsInput{TableName: aws.String(tableName)})
+	if err != nil {
+		return t, fmt.Errorf("could not describe backups of table %q: %w", tableName, err)
+	}
+	if d := backups.ContinuousBackupsDescription; d != nil && d.PointInTimeRecoveryDescription != nil &&
+		d.PointInTimeRecoveryDescription.PointInTimeRecoveryStatus == types.PointInTimeRecoveryStatusEnabled {
+		t.PointInTimeRecoverySpecification = &cloudformation.AWSDynamoDBTable_PointInTimeRecoverySpecification{
+			PointInTimeRecoveryEnabled: true,
+		}
+	}
+
+	var token *string
+	for {
+		tags, err := db.ListTagsOfResource(ctx, &dynamodb.ListTagsOfResourceInput{
+			ResourceArn: out.Table.TableArn,
+			NextToken:   token,
+		})
+		if err != nil {
+			return t, fmt.Errorf("could not list tags of table %q: %w", tableName, err)
+		}
+		for _, tag := range tags.Tags {
+			t.Tags = append(t.Tags, cloudformation.Tag{Key: aws.ToString(tag.Key), Value: aws.ToString(tag.Value)})
+		}
+		if token = tags.NextToken; token == nil {
+			break
+		}
+	}
+	sort.Slice(t.Tags, func(i, j int) bool { return t.Tags[i].Key < t.Tags[j].Key })
+	return t, nil
+}
+
+// Template puts tables into CloudFormation template, under logical IDs made of table names,
+// e.g. table "legacy-users" becomes "LegacyUsers". Call YAML or JSON on it to get the template file.
+func Template(tables ...cloudformation.AWSDynamoDBTable) *cloudformation.Template {
+	template := cloudformation.NewTemplate()
+	for _, t := range tables {
+		t := t
+		id := logicalID(t.TableName)
+		for n := 2; template.Resources[id] != nil; n++ {
+			id = fmt.Sprintf("%s%d", logicalID(t.TableName), n)
+		}
+		template.Resources[id] = &t
+	}
+	return template
+}
+
+// logicalID turns table name into alphanumeric logical ID, that CloudFormation requires.
+func logicalID(tableName string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range tableName {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) || r > unicode.MaxASCII {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "Table"
+	}
+	return b.String()
+}
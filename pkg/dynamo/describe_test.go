@@ -0,0 +1,59 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeCloudFormation(t *testing.T) {
+	ctx := context.Background()
+	names := make(map[string]string)
+	db, cleanup := dynamo.SetupTables(t, ctx, "./testdata/template.yml", dynamo.WithUniqueNames(names))
+	defer cleanup()
+	want, err := dynamo.LoadTables("./testdata/template.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for logical, physical := range names {
+		got, err := dynamo.DescribeCloudFormation(ctx, db, physical)
+		assert.NoError(t, err)
+
+		table := want[logical]
+		table.TableName = physical
+		assert.Equal(t, dynamo.FromCloudFormationToCreateInput(table), dynamo.FromCloudFormationToCreateInput(got), logical)
+		assert.Equal(t, table.TimeToLiveSpecification, got.TimeToLiveSpecification, logical)
+		assert.Equal(t, table.PointInTimeRecoverySpecification, got.PointInTimeRecoverySpecification, logical)
+		assert.ElementsMatch(t, table.Tags, got.Tags, logical)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	want, err := dynamo.LoadTables("./testdata/template.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := want["SettingsTable"]
+	settings.TableName = "legacy-settings_table"
+
+	out, err := dynamo.Template(want["PartitionKeyTable"], settings).YAML()
+	assert.NoError(t, err)
+	f, err := ioutil.TempFile("", "template-*.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Write(out)
+	f.Close()
+
+	got, err := dynamo.LoadTables(f.Name())
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, want["PartitionKeyTable"], got["PartitionKeyTable"])
+	assert.Equal(t, settings, got["LegacySettingsTable"])
+}
@@ -0,0 +1,255 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+// DiffKind tells which part of the table differs.
+type DiffKind string
+
+const (
+	DiffMissingTable         DiffKind = "table"
+	DiffKeySchema            DiffKind = "key schema"
+	DiffAttributeDefinitions DiffKind = "attribute definitions"
+	// DiffMissingIndex means that index from the template doesn't exist.
+	DiffMissingIndex DiffKind = "missing index"
+	// DiffExtraIndex means that index exists, but it's not in the template.
+	DiffExtraIndex            DiffKind = "extra index"
+	DiffProjection            DiffKind = "projection"
+	DiffBillingMode           DiffKind = "billing mode"
+	DiffProvisionedThroughput DiffKind = "provisioned throughput"
+	DiffStream                DiffKind = "stream"
+	DiffTimeToLive            DiffKind = "time to live"
+)
+
+// Diff is a single difference between the table in the template and the existing one.
+// Want and Got are human readable descriptions of the template and the existing table.
+type Diff struct {
+	Kind DiffKind
+	// Index is the name of the secondary index the difference is about, empty for the table itself.
+	Index string
+	// Global tells whether Index is global or local secondary index.
+	Global bool
+	Want   string
+	Got    string
+}
+
+func (d Diff) String() string {
+	if d.Index == "" {
+		return fmt.Sprintf("%s: want %s, got %s", d.Kind, d.Want, d.Got)
+	}
+	index := fmt.Sprintf("local index %q", d.Index)
+	if d.Global {
+		index = fmt.Sprintf("global index %q", d.Index)
+	}
+	if d.Kind == DiffMissingIndex || d.Kind == DiffExtraIndex {
+		return fmt.Sprintf("%s: want %s, got %s", index, d.Want, d.Got)
+	}
+	return fmt.Sprintf("%s %s: want %s, got %s", index, d.Kind, d.Want, d.Got)
+}
+
+// DetectDrift compares table from the CloudFormation template with the existing table of the same name.
+// It returns all the differences: key schema, attribute definitions, secondary indexes, their projections,
+// billing mode, provisioned throughput, stream and time to live. No differences mean no drift.
+// Table that doesn't exist is reported as DiffMissingTable.
+func DetectDrift(ctx context.Context, db *dynamodb.Client, table cloudformation.AWSDynamoDBTable) ([]Diff, error) {
+	out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table.TableName)})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return []Diff{{Kind: DiffMissingTable, Want: table.TableName, Got: "none"}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not describe table %q: %w", table.TableName, err)
+	}
+	ttl, err := db.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String(table.TableName)})
+	if err != nil {
+		return nil, fmt.Errorf("could not describe time to live of table %q: %w", table.TableName, err)
+	}
+	return Drift(table, *out.Table, ttl.TimeToLiveDescription), nil
+}
+
+// Drift compares table from the CloudFormation template with the description of existing table
+// and its time to live. See DetectDrift.
+func Drift(table cloudformation.AWSDynamoDBTable, desc types.TableDescription, ttl *types.TimeToLiveDescription) []Diff {
+	want := FromCloudFormationToCreateInput(table)
+	diffs := schemaDifferences(want, desc)
+	compare := func(kind DiffKind, want, got string) {
+		if want != got {
+			diffs = append(diffs, Diff{Kind: kind, Want: want, Got: got})
+		}
+	}
+
+	gotBilling := billingModeFromDescription(desc)
+	compare(DiffBillingMode, string(want.BillingMode), string(gotBilling))
+	if want.BillingMode == types.BillingModeProvisioned && gotBilling == types.BillingModeProvisioned {
+		compare(DiffProvisionedThroughput, formatThroughput(want.ProvisionedThroughput), formatThroughputDescription(desc.ProvisionedThroughput))
+		gotGlobal := map[string]*types.ProvisionedThroughputDescription{}
+		for _, idx := range desc.GlobalSecondaryIndexes {
+			gotGlobal[aws.ToString(idx.IndexName)] = idx.ProvisionedThroughput
+		}
+		for _, idx := range want.GlobalSecondaryIndexes {
+			got, ok := gotGlobal[aws.ToString(idx.IndexName)]
+			if w, g := formatThroughput(idx.ProvisionedThroughput), formatThroughputDescription(got); ok && w != g {
+				diffs = append(diffs, Diff{Kind: DiffProvisionedThroughput, Index: aws.ToString(idx.IndexName), Global: true, Want: w, Got: g})
+			}
+		}
+	}
+
+	wantStream, gotStream := "disabled", "disabled"
+	if s := want.StreamSpecification; s != nil && aws.ToBool(s.StreamEnabled) {
+		wantStream = string(s.StreamViewType)
+	}
+	if s := desc.StreamSpecification; s != nil && aws.ToBool(s.StreamEnabled) {
+		gotStream = string(s.StreamViewType)
+	}
+	compare(DiffStream, wantStream, gotStream)
+
+	wantTTL, gotTTL := "disabled", "disabled"
+	if s := table.TimeToLiveSpecification; s != nil && s.Enabled {
+		wantTTL = s.AttributeName
+	}
+	if ttl != nil && (ttl.TimeToLiveStatus == types.TimeToLiveStatusEnabled || ttl.TimeToLiveStatus == types.TimeToLiveStatusEnabling) {
+		gotTTL = aws.ToString(ttl.AttributeName)
+	}
+	compare(DiffTimeToLive, wantTTL, gotTTL)
+	return diffs
+}
+
+// schemaDifferences compares parts of the table, that cannot be changed without recreating it
+// or its indexes: key schema, attribute definitions and secondary indexes.
+func schemaDifferences(want dynamodb.CreateTableInput, got types.TableDescription) []Diff {
+	var diffs []Diff
+	compare := func(kind DiffKind, want, got string) {
+		if want != got {
+			diffs = append(diffs, Diff{Kind: kind, Want: want, Got: got})
+		}
+	}
+
+	compare(DiffKeySchema, formatKeySchema(want.KeySchema), formatKeySchema(got.KeySchema))
+	compare(DiffAttributeDefinitions, formatAttributes(want.AttributeDefinitions), formatAttributes(got.AttributeDefinitions))
+
+	wantLocal, gotLocal := map[string]indexSchema{}, map[string]indexSchema{}
+	for _, idx := range want.LocalSecondaryIndexes {
+		wantLocal[aws.ToString(idx.IndexName)] = indexSchema{idx.KeySchema, idx.Projection}
+	}
+	for _, idx := range got.LocalSecondaryIndexes {
+		gotLocal[aws.ToString(idx.IndexName)] = indexSchema{idx.KeySchema, idx.Projection}
+	}
+	diffs = append(diffs, indexDifferences(false, wantLocal, gotLocal)...)
+
+	wantGlobal, gotGlobal := map[string]indexSchema{}, map[string]indexSchema{}
+	for _, idx := range want.GlobalSecondaryIndexes {
+		wantGlobal[aws.ToString(idx.IndexName)] = indexSchema{idx.KeySchema, idx.Projection}
+	}
+	for _, idx := range got.GlobalSecondaryIndexes {
+		gotGlobal[aws.ToString(idx.IndexName)] = indexSchema{idx.KeySchema, idx.Projection}
+	}
+	diffs = append(diffs, indexDifferences(true, wantGlobal, gotGlobal)...)
+	return diffs
+}
+
+type indexSchema struct {
+	keys       []types.KeySchemaElement
+	projection *types.Projection
+}
+
+func (i indexSchema) String() string {
+	if i.projection == nil {
+		return formatKeySchema(i.keys)
+	}
+	return formatKeySchema(i.keys) + " " + formatProjection(i.projection)
+}
+
+func indexDifferences(global bool, want, got map[string]indexSchema) []Diff {
+	names := map[string]bool{}
+	for name := range want {
+		names[name] = true
+	}
+	for name := range got {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var diffs []Diff
+	for _, name := range sorted {
+		w, wok := want[name]
+		g, gok := got[name]
+		diff := Diff{Index: name, Global: global}
+		switch {
+		case !gok:
+			diff.Kind, diff.Want, diff.Got = DiffMissingIndex, w.String(), "none"
+		case !wok:
+			diff.Kind, diff.Want, diff.Got = DiffExtraIndex, "none", g.String()
+		case formatKeySchema(w.keys) != formatKeySchema(g.keys):
+			diff.Kind, diff.Want, diff.Got = DiffKeySchema, formatKeySchema(w.keys), formatKeySchema(g.keys)
+		case formatProjection(w.projection) != formatProjection(g.projection):
+			diff.Kind, diff.Want, diff.Got = DiffProjection, formatProjection(w.projection), formatProjection(g.projection)
+		default:
+			continue
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+func formatKeySchema(keys []types.KeySchemaElement) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, aws.ToString(key.AttributeName)+" "+string(key.KeyType))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func formatAttributes(attrs []types.AttributeDefinition) string {
+	parts := make([]string, 0, len(attrs))
+	for _, attr := range attrs {
+		parts = append(parts, aws.ToString(attr.AttributeName)+" "+string(attr.AttributeType))
+	}
+	sort.Strings(parts)
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func formatProjection(p *types.Projection) string {
+	if p == nil {
+		return "none"
+	}
+	projection := string(p.ProjectionType)
+	if len(p.NonKeyAttributes) > 0 {
+		attrs := append([]string{}, p.NonKeyAttributes...)
+		sort.Strings(attrs)
+		projection += " (" + strings.Join(attrs, ", ") + ")"
+	}
+	return projection
+}
+
+func formatThroughput(p *types.ProvisionedThroughput) string {
+	if p == nil {
+		return "none"
+	}
+	return formatCapacity(aws.ToInt64(p.ReadCapacityUnits), aws.ToInt64(p.WriteCapacityUnits))
+}
+
+func formatThroughputDescription(p *types.ProvisionedThroughputDescription) string {
+	if p == nil {
+		return "none"
+	}
+	return formatCapacity(aws.ToInt64(p.ReadCapacityUnits), aws.ToInt64(p.WriteCapacityUnits))
+}
+
+func formatCapacity(read, write int64) string {
+	return strconv.FormatInt(read, 10) + " RCU, " + strconv.FormatInt(write, 10) + " WCU"
+}
@@ -0,0 +1,78 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"testing"
+
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectDrift(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := dynamo.SetupTable(t, ctx, "CompositePrimaryKeyAndSingleGlobalIndexTable", "./testdata/template.yml")
+	defer cleanup()
+	table, err := dynamo.LoadTable("./testdata/template.yml", "CompositePrimaryKeyAndSingleGlobalIndexTable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("no drift", func(t *testing.T) {
+		diffs, err := dynamo.DetectDrift(ctx, db, table)
+		assert.NoError(t, err)
+		assert.Empty(t, diffs)
+	})
+
+	t.Run("drift", func(t *testing.T) {
+		drifted := table
+		drifted.GlobalSecondaryIndexes = []cloudformation.AWSDynamoDBTable_GlobalSecondaryIndex{
+			table.GlobalSecondaryIndexes[0],
+			{
+				IndexName:  "ByPartition",
+				KeySchema:  []cloudformation.AWSDynamoDBTable_KeySchema{{AttributeName: "gsi1_pk", KeyType: "HASH"}},
+				Projection: &cloudformation.AWSDynamoDBTable_Projection{ProjectionType: "KEYS_ONLY"},
+			},
+		}
+		drifted.GlobalSecondaryIndexes[0].Projection = &cloudformation.AWSDynamoDBTable_Projection{
+			ProjectionType:   "INCLUDE",
+			NonKeyAttributes: []string{"name"},
+		}
+		drifted.BillingMode = "PROVISIONED"
+		drifted.ProvisionedThroughput = &cloudformation.AWSDynamoDBTable_ProvisionedThroughput{ReadCapacityUnits: 5, WriteCapacityUnits: 5}
+		drifted.StreamSpecification = &cloudformation.AWSDynamoDBTable_StreamSpecification{StreamViewType: "KEYS_ONLY"}
+		drifted.TimeToLiveSpecification = &cloudformation.AWSDynamoDBTable_TimeToLiveSpecification{AttributeName: "ttl", Enabled: true}
+
+		diffs, err := dynamo.DetectDrift(ctx, db, drifted)
+		assert.NoError(t, err)
+		assert.Equal(t, []dynamo.Diff{
+			{Kind: dynamo.DiffMissingIndex, Index: "ByPartition", Global: true, Want: "[gsi1_pk HASH] KEYS_ONLY", Got: "none"},
+			{Kind: dynamo.DiffProjection, Index: "GlobalSecondaryIndex1", Global: true, Want: "INCLUDE (name)", Got: "ALL"},
+			{Kind: dynamo.DiffBillingMode, Want: "PROVISIONED", Got: "PAY_PER_REQUEST"},
+			{Kind: dynamo.DiffStream, Want: "KEYS_ONLY", Got: "disabled"},
+			{Kind: dynamo.DiffTimeToLive, Want: "ttl", Got: "disabled"},
+		}, diffs)
+		assert.Equal(t, `global index "ByPartition": want [gsi1_pk HASH] KEYS_ONLY, got none`, diffs[0].String())
+		assert.Equal(t, `global index "GlobalSecondaryIndex1" projection: want INCLUDE (name), got ALL`, diffs[1].String())
+	})
+
+	t.Run("extra index", func(t *testing.T) {
+		drifted := table
+		drifted.GlobalSecondaryIndexes = nil
+
+		diffs, err := dynamo.DetectDrift(ctx, db, drifted)
+		assert.NoError(t, err)
+		assert.Equal(t, []dynamo.Diff{
+			{Kind: dynamo.DiffExtraIndex, Index: "GlobalSecondaryIndex1", Global: true, Want: "none", Got: "[gsi1_pk HASH, gsi1_sk RANGE] ALL"},
+		}, diffs)
+	})
+
+	t.Run("missing table", func(t *testing.T) {
+		missing := table
+		missing.TableName = "MissingTable"
+
+		diffs, err := dynamo.DetectDrift(ctx, db, missing)
+		assert.NoError(t, err)
+		assert.Equal(t, []dynamo.Diff{{Kind: dynamo.DiffMissingTable, Want: "MissingTable", Got: "none"}}, diffs)
+	})
+}
@@ -0,0 +1,217 @@
+package dynamo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"dynamodb-with-go/pkg/dynamo/ddbjson"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/sanathkr/yaml"
+)
+
+// Fixtures are items keyed by names of the tables they belong to.
+type Fixtures map[string][]map[string]types.AttributeValue
+
+// ReadFixtures reads items from the file under `path`. Files with .yml or .yaml extension are YAML,
+// others are JSON. The file holds either a list of items, that belong to `table`, or an object with
+// lists of items keyed by table names, e.g. the request items of `aws dynamodb batch-write-item`.
+//
+// Items are either in DynamoDB JSON, e.g. {"pk": {"S": "1234"}}, or plain ones, e.g. {"pk": "1234"},
+// where strings become S, numbers N, booleans BOOL, lists L and objects M. Item is in DynamoDB JSON
+// when every attribute is an object with exactly one type descriptor.
+func ReadFixtures(path, table string) (Fixtures, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yml" || ext == ".yaml" {
+		if b, err = yaml.YAMLToJSON(b); err != nil {
+			return nil, fmt.Errorf("invalid fixtures %s: %w", path, err)
+		}
+	}
+	fixtures, err := parseFixtures(b, table)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fixtures %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+func parseFixtures(b []byte, table string) (Fixtures, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var raw interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	lists := map[string]interface{}{table: raw}
+	if m, ok := raw.(map[string]interface{}); ok {
+		lists = m
+	}
+	fixtures := Fixtures{}
+	for name, list := range lists {
+		items, ok := list.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("items of table %q must be a list", name)
+		}
+		for n, i := range items {
+			item, err := parseItem(i)
+			if err != nil {
+				return nil, fmt.Errorf("table %q, item %d: %w", name, n, err)
+			}
+			fixtures[name] = append(fixtures[name], item)
+		}
+	}
+	return fixtures, nil
+}
+
+func parseItem(v interface{}) (map[string]types.AttributeValue, error) {
+	attrs, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("item must be an object, got %T", v)
+	}
+	// Unwrap requests of BatchWriteItem.
+	if put, ok := attrs["PutRequest"].(map[string]interface{}); ok && len(attrs) == 1 {
+		if attrs, ok = put["Item"].(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("PutRequest without Item")
+		}
+	}
+
+	if isDynamoDBJSON(attrs) {
+		b, err := json.Marshal(attrs)
+		if err != nil {
+			return nil, err
+		}
+		return ddbjson.UnmarshalItem(b)
+	}
+	item := make(map[string]types.AttributeValue, len(attrs))
+	for name, attr := range attrs {
+		av, err := plainValue(attr)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", name, err)
+		}
+		item[name] = av
+	}
+	return item, nil
+}
+
+var typeDescriptors = map[string]bool{
+	"S": true, "N": true, "B": true, "BOOL": true, "NULL": true,
+	"SS": true, "NS": true, "BS": true, "L": true, "M": true,
+}
+
+func isDynamoDBJSON(attrs map[string]interface{}) bool {
+	if len(attrs) == 0 {
+		return false
+	}
+	for _, attr := range attrs {
+		m, ok := attr.(map[string]interface{})
+		if !ok || len(m) != 1 {
+			return false
+		}
+		for typ := range m {
+			if !typeDescriptors[typ] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func plainValue(v interface{}) (types.AttributeValue, error) {
+	switch x := v.(type) {
+	case string:
+		return &types.AttributeValueMemberS{Value: x}, nil
+	case json.Number:
+		return &types.AttributeValueMemberN{Value: x.String()}, nil
+	case bool:
+		return &types.AttributeValueMemberBOOL{Value: x}, nil
+	case nil:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case []interface{}:
+		list := make([]types.AttributeValue, 0, len(x))
+		for n, e := range x {
+			av, err := plainValue(e)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", n, err)
+			}
+			list = append(list, av)
+		}
+		return &types.AttributeValueMemberL{Value: list}, nil
+	case map[string]interface{}:
+		m := make(map[string]types.AttributeValue, len(x))
+		for name, e := range x {
+			av, err := plainValue(e)
+			if err != nil {
+				return nil, fmt.Errorf("attribute %q: %w", name, err)
+			}
+			m[name] = av
+		}
+		return &types.AttributeValueMemberM{Value: m}, nil
+	default:
+		return nil, fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+// LoadFixtures reads items from the fixture files (see ReadFixtures) and writes them to the tables
+// with BatchWriteItem. Lists of items without table name go to `table`.
+func LoadFixtures(ctx context.Context, db Client, table string, paths ...string) error {
+	return loadFixtures(ctx, db, table, nil, paths)
+}
+
+// loadFixtures writes fixtures to the tables. When `names` is set, fixtures are keyed by logical
+// names of the tables, that are replaced with physical ones.
+func loadFixtures(ctx context.Context, db Client, table string, names map[string]string, paths []string) error {
+	for _, path := range paths {
+		fixtures, err := ReadFixtures(path, table)
+		if err != nil {
+			return err
+		}
+		tables := make([]string, 0, len(fixtures))
+		for name := range fixtures {
+			tables = append(tables, name)
+		}
+		sort.Strings(tables)
+		for _, name := range tables {
+			if name == "" {
+				return fmt.Errorf("fixtures %s: items must be keyed by table name", path)
+			}
+			physical := name
+			if names != nil {
+				if physical = names[name]; physical == "" {
+					return fmt.Errorf("fixtures %s: table %q is not set up", path, name)
+				}
+			}
+			if err := writeItems(ctx, db, physical, fixtures[name]); err != nil {
+				return fmt.Errorf("fixtures %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeItems puts items to the table in batches, retrying unprocessed items until they are all written.
+// Batches are written one by one, so the last of the items with the same key wins.
+func writeItems(ctx context.Context, db Client, table string, items []map[string]types.AttributeValue) error {
+	w := newBatchWriter(ctx, db, batchOptions{concurrency: 1})
+	var err error
+	for _, item := range items {
+		if err = w.Put(table, item); err != nil {
+			break
+		}
+	}
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write items to table %q: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"dynamodb-with-go/pkg/dynamo/memdb"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadFixtures(t *testing.T) {
+	owner := &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+		"name":   &types.AttributeValueMemberS{Value: "John"},
+		"emails": &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "john@example.com"}}},
+	}}
+	plain := map[string]types.AttributeValue{
+		"pk":         &types.AttributeValueMemberS{Value: "1"},
+		"count":      &types.AttributeValueMemberN{Value: "10"},
+		"active":     &types.AttributeValueMemberBOOL{Value: true},
+		"owner":      owner,
+		"deleted_at": &types.AttributeValueMemberNULL{Value: true},
+	}
+
+	fixtures, err := dynamo.ReadFixtures("./testdata/fixtures/items.json", "Table")
+	assert.NoError(t, err)
+	withSet := map[string]types.AttributeValue{"tags": &types.AttributeValueMemberSS{Value: []string{"a", "b"}}}
+	for name, av := range plain {
+		withSet[name] = av
+	}
+	assert.Equal(t, dynamo.Fixtures{"Table": {withSet}}, fixtures)
+
+	for _, path := range []string{"./testdata/fixtures/items.plain.json", "./testdata/fixtures/items.yml"} {
+		fixtures, err := dynamo.ReadFixtures(path, "Table")
+		assert.NoError(t, err, path)
+		assert.Equal(t, dynamo.Fixtures{"Table": {plain}}, fixtures, path)
+	}
+}
+
+func TestReadFixturesKeyedByTable(t *testing.T) {
+	fixtures, err := dynamo.ReadFixtures("./testdata/fixtures/tables.json", "")
+	assert.NoError(t, err)
+	assert.Equal(t, dynamo.Fixtures{
+		"PartitionKeyTable": {
+			{"pk": &types.AttributeValueMemberS{Value: "1"}},
+			{"pk": &types.AttributeValueMemberS{Value: "2"}},
+		},
+		"CompositePrimaryKeyTable": {
+			{"pk": &types.AttributeValueMemberS{Value: "1"}, "sk": &types.AttributeValueMemberS{Value: "a"}},
+		},
+	}, fixtures)
+}
+
+func TestSetupTableWithFixtures(t *testing.T) {
+	ctx := context.Background()
+	names := map[string]string{}
+	db, cleanup := dynamo.SetupTable(t, ctx, "PartitionKeyTable", "./testdata/template.yml",
+		dynamo.WithUniqueNames(names), dynamo.WithFixtures("./testdata/fixtures/items.yml"))
+	defer cleanup()
+
+	out, err := db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(names["PartitionKeyTable"]),
+		Key:       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: "1"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "10"}, out.Item["count"])
+}
+
+func TestLoadFixturesUnprocessedItems(t *testing.T) {
+	ctx := context.Background()
+	mem := memdb.New()
+	mem.LimitBatch(10)
+	srv := httptest.NewServer(mem)
+	defer srv.Close()
+	db, err := dynamo.NewClient(ctx, dynamo.WithEndpoint(srv.URL))
+	assert.NoError(t, err)
+	table, err := dynamo.LoadTable("./testdata/template.yml", "PartitionKeyTable")
+	assert.NoError(t, err)
+	assert.NoError(t, dynamo.CreateTable(ctx, db, table))
+
+	dir, err := ioutil.TempDir("", "fixtures")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "items.json")
+	items := "["
+	for i := 0; i < 30; i++ {
+		if i > 0 {
+			items += ","
+		}
+		items += `{"pk": "` + strconv.Itoa(i) + `"}`
+	}
+	assert.NoError(t, ioutil.WriteFile(path, []byte(items+"]"), 0600))
+
+	err = dynamo.LoadFixtures(ctx, struct{ dynamo.Client }{db}, "PartitionKeyTable", path)
+	assert.NoError(t, err)
+
+	out, err := db.Scan(ctx, &dynamodb.ScanInput{
+		TableName: aws.String("PartitionKeyTable"),
+		Select:    types.SelectCount,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(30), out.Count)
+}
+
+func TestSetupTablesWithFixtures(t *testing.T) {
+	ctx := context.Background()
+	names := map[string]string{}
+	db, cleanup := dynamo.SetupTables(t, ctx, "./testdata/template.yml",
+		dynamo.WithUniqueNames(names), dynamo.WithFixtures("./testdata/fixtures/tables.json"))
+	defer cleanup()
+
+	for name, count := range map[string]int32{"PartitionKeyTable": 2, "CompositePrimaryKeyTable": 1, "SettingsTable": 0} {
+		out, err := db.Scan(ctx, &dynamodb.ScanInput{
+			TableName: aws.String(names[name]),
+			Select:    types.SelectCount,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, count, out.Count, name)
+	}
+}
@@ -0,0 +1,210 @@
+package dynamo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+
+	"dynamodb-with-go/pkg/dynamo/ddbjson"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// EnvUpdateGolden is the environment variable, that makes AssertTable write golden files
+// instead of comparing tables with them, e.g. UPDATE_GOLDEN=1 go test ./...
+const EnvUpdateGolden = "UPDATE_GOLDEN"
+
+// AssertTable compares contents of the table with the golden file under `golden`.
+// Items are sorted by primary key and rendered in DynamoDB JSON, one attribute per line,
+// so that a missing item or a wrong attribute shows up as a readable diff.
+// Run tests with UPDATE_GOLDEN=1 to write current contents of the table to the golden file.
+func AssertTable(t testing.TB, ctx context.Context, db Client, tableName, golden string) {
+	t.Helper()
+	update, err := updateGolden()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DumpTable(ctx, db, tableName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(golden)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden file %s does not exist, run tests with %s=1 to create it", golden, EnvUpdateGolden)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(want), string(got),
+		"table %s differs from golden file %s, run tests with %s=1 if the change is expected", tableName, golden, EnvUpdateGolden)
+}
+
+func updateGolden() (bool, error) {
+	v := os.Getenv(EnvUpdateGolden)
+	if v == "" {
+		return false, nil
+	}
+	update, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", EnvUpdateGolden, err)
+	}
+	return update, nil
+}
+
+// DumpTable scans the whole table and renders its items sorted by primary key.
+// Elements of sets are sorted too, as DynamoDB does not keep their order.
+func DumpTable(ctx context.Context, db Client, tableName string) ([]byte, error) {
+	out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, k := range out.Table.KeySchema {
+		if k.KeyType == types.KeyTypeHash {
+			keys = append([]string{aws.ToString(k.AttributeName)}, keys...)
+		} else {
+			keys = append(keys, aws.ToString(k.AttributeName))
+		}
+	}
+
+	items, err := NewScanPaginator(db, &dynamodb.ScanInput{
+		TableName:      aws.String(tableName),
+		ConsistentRead: aws.Bool(true),
+	}).Items(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not scan table %q: %w", tableName, err)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, k := range keys {
+			if c := compareKeys(items[i][k], items[j][k]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, item := range items {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  {")
+		for n, name := range sortedNames(item) {
+			if n > 0 {
+				buf.WriteString(",")
+			}
+			v, err := ddbjson.Marshal(sortSet(item[name]))
+			if err != nil {
+				return nil, fmt.Errorf("attribute %q: %w", name, err)
+			}
+			k, _ := json.Marshal(name)
+			fmt.Fprintf(&buf, "\n    %s: %s", k, v)
+		}
+		buf.WriteString("\n  }")
+	}
+	if len(items) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]\n")
+	return buf.Bytes(), nil
+}
+
+func sortedNames(item map[string]types.AttributeValue) []string {
+	names := make([]string, 0, len(item))
+	for name := range item {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// compareKeys compares values of key attributes, numbers by their value.
+func compareKeys(a, b types.AttributeValue) int {
+	switch x := a.(type) {
+	case *types.AttributeValueMemberS:
+		if y, ok := b.(*types.AttributeValueMemberS); ok {
+			return compareStrings(x.Value, y.Value)
+		}
+	case *types.AttributeValueMemberN:
+		if y, ok := b.(*types.AttributeValueMemberN); ok {
+			xr, xok := new(big.Rat).SetString(x.Value)
+			yr, yok := new(big.Rat).SetString(y.Value)
+			if xok && yok {
+				return xr.Cmp(yr)
+			}
+			return compareStrings(x.Value, y.Value)
+		}
+	case *types.AttributeValueMemberB:
+		if y, ok := b.(*types.AttributeValueMemberB); ok {
+			return bytes.Compare(x.Value, y.Value)
+		}
+	}
+	return 0
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func sortSets(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	sorted := make(map[string]types.AttributeValue, len(item))
+	for name, av := range item {
+		sorted[name] = sortSet(av)
+	}
+	return sorted
+}
+
+func sortSet(av types.AttributeValue) types.AttributeValue {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberSS:
+		ss := append([]string(nil), v.Value...)
+		sort.Strings(ss)
+		return &types.AttributeValueMemberSS{Value: ss}
+	case *types.AttributeValueMemberNS:
+		ns := append([]string(nil), v.Value...)
+		sort.Slice(ns, func(i, j int) bool {
+			return compareKeys(&types.AttributeValueMemberN{Value: ns[i]}, &types.AttributeValueMemberN{Value: ns[j]}) < 0
+		})
+		return &types.AttributeValueMemberNS{Value: ns}
+	case *types.AttributeValueMemberBS:
+		bs := append([][]byte(nil), v.Value...)
+		sort.Slice(bs, func(i, j int) bool { return bytes.Compare(bs[i], bs[j]) < 0 })
+		return &types.AttributeValueMemberBS{Value: bs}
+	case *types.AttributeValueMemberL:
+		list := make([]types.AttributeValue, 0, len(v.Value))
+		for _, e := range v.Value {
+			list = append(list, sortSet(e))
+		}
+		return &types.AttributeValueMemberL{Value: list}
+	case *types.AttributeValueMemberM:
+		return &types.AttributeValueMemberM{Value: sortSets(v.Value)}
+	}
+	return av
+}
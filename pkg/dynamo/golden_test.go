@@ -0,0 +1,83 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssertTable(t *testing.T) {
+	ctx := context.Background()
+	names := map[string]string{}
+	db, cleanup := dynamo.SetupTable(t, ctx, "CompositePrimaryKeyTable", "./testdata/template.yml",
+		dynamo.WithUniqueNames(names), dynamo.WithFixtures("./testdata/fixtures/unsorted.json"))
+	defer cleanup()
+
+	dynamo.AssertTable(t, ctx, db, names["CompositePrimaryKeyTable"], "./testdata/golden/unsorted.json")
+	// Wrapped clients, e.g. instrumented ones, work too.
+	dynamo.AssertTable(t, ctx, struct{ dynamo.Client }{db}, names["CompositePrimaryKeyTable"], "./testdata/golden/unsorted.json")
+}
+
+func TestDumpTable(t *testing.T) {
+	ctx := context.Background()
+	names := map[string]string{}
+	db, cleanup := dynamo.SetupTable(t, ctx, "CompositePrimaryKeyTable", "./testdata/template.yml", dynamo.WithUniqueNames(names))
+	defer cleanup()
+	tableName := names["CompositePrimaryKeyTable"]
+
+	got, err := dynamo.DumpTable(ctx, db, tableName)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]\n", string(got))
+
+	_, err = db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(tableName),
+		Item: map[string]types.AttributeValue{
+			"pk":    &types.AttributeValueMemberS{Value: "1"},
+			"sk":    &types.AttributeValueMemberS{Value: "a"},
+			"sizes": &types.AttributeValueMemberNS{Value: []string{"10", "9", "-1.5"}},
+		},
+	})
+	assert.NoError(t, err)
+
+	got, err = dynamo.DumpTable(ctx, db, tableName)
+	assert.NoError(t, err)
+	assert.Equal(t, `[
+  {
+    "pk": {"S":"1"},
+    "sizes": {"NS":["-1.5","9","10"]},
+    "sk": {"S":"a"}
+  }
+]
+`, string(got))
+}
+
+func TestAssertTableUpdate(t *testing.T) {
+	ctx := context.Background()
+	names := map[string]string{}
+	db, cleanup := dynamo.SetupTable(t, ctx, "CompositePrimaryKeyTable", "./testdata/template.yml",
+		dynamo.WithUniqueNames(names), dynamo.WithFixtures("./testdata/fixtures/unsorted.json"))
+	defer cleanup()
+	dir, err := ioutil.TempDir("", "golden")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	golden := filepath.Join(dir, "testdata", "unsorted.json")
+
+	setenv(t, dynamo.EnvUpdateGolden, "1")
+	dynamo.AssertTable(t, ctx, db, names["CompositePrimaryKeyTable"], golden)
+	got, err := ioutil.ReadFile(golden)
+	assert.NoError(t, err)
+	want, err := ioutil.ReadFile("./testdata/golden/unsorted.json")
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+
+	setenv(t, dynamo.EnvUpdateGolden, "false")
+	dynamo.AssertTable(t, ctx, db, names["CompositePrimaryKeyTable"], golden)
+}
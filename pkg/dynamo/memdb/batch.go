@@ -0,0 +1,221 @@
+package memdb
+
+import (
+	"sort"
+
+	"dynamodb-with-go/pkg/dynamo/ddbjson"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// LimitBatch makes batch operations process at most n items per request and return the rest
+// as unprocessed, the way throttled DynamoDB does. Zero turns the limit off.
+func (db *DB) LimitBatch(n int) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.batchLimit = n
+}
+
+type writeRequest struct {
+	PutRequest *struct {
+		Item ddbjson.Item
+	} `json:",omitempty"`
+	DeleteRequest *struct {
+		Key ddbjson.Item
+	} `json:",omitempty"`
+}
+
+type batchWriteItemInput struct {
+	RequestItems           map[string][]writeRequest
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+}
+
+func (db *DB) batchWriteItem(body []byte) (interface{}, error) {
+	var in batchWriteItemInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	total := 0
+	for _, requests := range in.RequestItems {
+		total += len(requests)
+	}
+	if total == 0 || total > 25 {
+		return nil, validationError("1 validation error detected: Value at 'requestItems' failed to satisfy constraint: Map value must satisfy constraint: Member must have length less than or equal to 25")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	names := make([]string, 0, len(in.RequestItems))
+	for name := range in.RequestItems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Validate the whole batch first, DynamoDB rejects it as a whole.
+	var changes []change
+	for _, name := range names {
+		t, err := db.table(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		for _, r := range in.RequestItems[name] {
+			var c change
+			switch {
+			case r.PutRequest != nil && r.DeleteRequest == nil:
+				if err := t.checkItem(r.PutRequest.Item); err != nil {
+					return nil, err
+				}
+				c = change{table: t, id: t.id(r.PutRequest.Item), item: copyItem(r.PutRequest.Item)}
+			case r.DeleteRequest != nil && r.PutRequest == nil:
+				if err := t.checkKey(r.DeleteRequest.Key); err != nil {
+					return nil, err
+				}
+				c = change{table: t, id: t.id(r.DeleteRequest.Key), delete: true}
+			default:
+				return nil, validationError("Supplied AttributeValue has more than one datatypes set, must contain exactly one of the supported datatypes")
+			}
+			if seen[c.id] {
+				return nil, validationError("Provided list of item keys contains duplicates")
+			}
+			seen[c.id] = true
+			changes = append(changes, c)
+		}
+	}
+
+	unprocessed := make(map[string][]writeRequest)
+	units := make(map[string]float64)
+	n := 0
+	for _, name := range names {
+		for _, r := range in.RequestItems[name] {
+			c := changes[n]
+			n++
+			if db.batchLimit > 0 && n > db.batchLimit {
+				unprocessed[name] = append(unprocessed[name], r)
+				continue
+			}
+			if c.delete {
+				units[name] += writeUnits(itemSize(c.table.items[c.id]))
+				delete(c.table.items, c.id)
+			} else {
+				units[name] += writeUnits(itemSize(c.item))
+				c.table.items[c.id] = c.item
+			}
+		}
+	}
+	out := map[string]interface{}{"UnprocessedItems": unprocessed}
+	if capacity(in.ReturnConsumedCapacity, "", 0) != nil {
+		consumed := make([]*consumedCapacity, 0, len(units))
+		for _, name := range names {
+			if u, ok := units[name]; ok {
+				consumed = append(consumed, &consumedCapacity{TableName: name, CapacityUnits: u})
+			}
+		}
+		out["ConsumedCapacity"] = consumed
+	}
+	return out, nil
+}
+
+type keysAndAttributes struct {
+	Keys                     []ddbjson.Item
+	ProjectionExpression     *string           `json:",omitempty"`
+	ExpressionAttributeNames map[string]string `json:",omitempty"`
+	ConsistentRead           *bool             `json:",omitempty"`
+}
+
+type batchGetItemInput struct {
+	RequestItems           map[string]keysAndAttributes
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+}
+
+func (db *DB) batchGetItem(body []byte) (interface{}, error) {
+	var in batchGetItemInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	total := 0
+	for _, r := range in.RequestItems {
+		total += len(r.Keys)
+	}
+	if total == 0 {
+		return nil, validationError("1 validation error detected: Value at 'requestItems' failed to satisfy constraint: Member must have length greater than or equal to 1")
+	}
+	if total > 100 {
+		return nil, validationError("Too many items requested for the BatchGetItem call")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	names := make([]string, 0, len(in.RequestItems))
+	for name := range in.RequestItems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Validate the whole batch first, DynamoDB rejects it as a whole.
+	tables := make(map[string]*table, len(names))
+	projections := make(map[string][]path, len(names))
+	for _, name := range names {
+		t, err := db.table(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+		r := in.RequestItems[name]
+		p := newParser(r.ExpressionAttributeNames, nil)
+		paths, err := p.projection(r.ProjectionExpression)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.done(); err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		for _, key := range r.Keys {
+			if err := t.checkKey(key); err != nil {
+				return nil, err
+			}
+			id := t.id(key)
+			if seen[id] {
+				return nil, validationError("Provided list of item keys contains duplicates")
+			}
+			seen[id] = true
+		}
+		tables[name], projections[name] = t, paths
+	}
+
+	responses := make(map[string][]ddbjson.Item)
+	unprocessed := make(map[string]keysAndAttributes)
+	units := make(map[string]float64)
+	n := 0
+	for _, name := range names {
+		r, t := in.RequestItems[name], tables[name]
+		responses[name] = []ddbjson.Item{}
+		for _, key := range r.Keys {
+			n++
+			if db.batchLimit > 0 && n > db.batchLimit {
+				u := unprocessed[name]
+				u.Keys = append(u.Keys, key)
+				u.ProjectionExpression, u.ExpressionAttributeNames, u.ConsistentRead = r.ProjectionExpression, r.ExpressionAttributeNames, r.ConsistentRead
+				unprocessed[name] = u
+				continue
+			}
+			i, ok := t.items[t.id(key)]
+			units[name] += readUnits(itemSize(i), aws.ToBool(r.ConsistentRead))
+			if ok {
+				responses[name] = append(responses[name], project(i, projections[name]))
+			}
+		}
+	}
+	out := map[string]interface{}{"Responses": responses, "UnprocessedKeys": unprocessed}
+	if capacity(in.ReturnConsumedCapacity, "", 0) != nil {
+		consumed := make([]*consumedCapacity, 0, len(units))
+		for _, name := range names {
+			if u, ok := units[name]; ok {
+				consumed = append(consumed, &consumedCapacity{TableName: name, CapacityUnits: u})
+			}
+		}
+		out["ConsumedCapacity"] = consumed
+	}
+	return out, nil
+}
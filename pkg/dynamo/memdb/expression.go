@@ -0,0 +1,1062 @@
+package memdb
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// This file implements the subset of DynamoDB expressions grammar:
+// condition, key condition, filter, update and projection expressions.
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokName
+	tokValue
+	tokNumber
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '#' || c == ':':
+			j := i + 1
+			for j < len(s) && isIdentChar(rune(s[j])) {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("Syntax error; token: %q", string(c))
+			}
+			kind := tokName
+			if c == ':' {
+				kind = tokValue
+			}
+			toks = append(toks, token{kind: kind, text: s[i:j]})
+			i = j
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(s) && unicode.IsDigit(rune(s[j])) {
+				j++
+			}
+			toks = append(toks, token{kind: tokNumber, text: s[i:j]})
+			i = j
+		case isIdentChar(c):
+			j := i
+			for j < len(s) && isIdentChar(rune(s[j])) {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: s[i:j]})
+			i = j
+		case strings.HasPrefix(s[i:], "<>"), strings.HasPrefix(s[i:], "<="), strings.HasPrefix(s[i:], ">="):
+			toks = append(toks, token{kind: tokPunct, text: s[i : i+2]})
+			i += 2
+		case strings.ContainsRune("()[],.=<>+-", c):
+			toks = append(toks, token{kind: tokPunct, text: string(c)})
+			i++
+		default:
+			return nil, fmt.Errorf("Syntax error; token: %q", string(c))
+		}
+	}
+	return append(toks, token{kind: tokEOF}), nil
+}
+
+func isIdentChar(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// pathElem is either attribute name or list index.
+type pathElem struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+type path []pathElem
+
+func (p path) String() string {
+	var b strings.Builder
+	for i, e := range p {
+		switch {
+		case e.isIndex:
+			fmt.Fprintf(&b, "[%d]", e.index)
+		case i > 0:
+			b.WriteString("." + e.name)
+		default:
+			b.WriteString(e.name)
+		}
+	}
+	return b.String()
+}
+
+func (p path) get(i item) (types.AttributeValue, bool) {
+	var cur types.AttributeValue = &types.AttributeValueMemberM{Value: i}
+	for _, e := range p {
+		if e.isIndex {
+			l, ok := cur.(*types.AttributeValueMemberL)
+			if !ok || e.index >= len(l.Value) {
+				return nil, false
+			}
+			cur = l.Value[e.index]
+			continue
+		}
+		m, ok := cur.(*types.AttributeValueMemberM)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m.Value[e.name]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func (p path) set(i item, av types.AttributeValue) error {
+	last := p[len(p)-1]
+	if len(p) == 1 {
+		i[last.name] = av
+		return nil
+	}
+	parent, ok := p[:len(p)-1].get(i)
+	if !ok {
+		return errInvalidPath
+	}
+	switch v := parent.(type) {
+	case *types.AttributeValueMemberL:
+		if !last.isIndex {
+			return errInvalidPath
+		}
+		if last.index >= len(v.Value) {
+			v.Value = append(v.Value, av)
+		} else {
+			v.Value[last.index] = av
+		}
+	case *types.AttributeValueMemberM:
+		if last.isIndex {
+			return errInvalidPath
+		}
+		v.Value[last.name] = av
+	default:
+		return errInvalidPath
+	}
+	return nil
+}
+
+func (p path) remove(i item) {
+	last := p[len(p)-1]
+	if len(p) == 1 {
+		delete(i, last.name)
+		return
+	}
+	parent, ok := p[:len(p)-1].get(i)
+	if !ok {
+		return
+	}
+	switch v := parent.(type) {
+	case *types.AttributeValueMemberL:
+		if last.isIndex && last.index < len(v.Value) {
+			v.Value = append(v.Value[:last.index], v.Value[last.index+1:]...)
+		}
+	case *types.AttributeValueMemberM:
+		delete(v.Value, last.name)
+	}
+}
+
+var errInvalidPath = fmt.Errorf("The document path provided in the update expression is invalid for update")
+
+// operand is a value in the expression: placeholder, document path or a function call.
+type operand interface {
+	eval(i item) (types.AttributeValue, bool)
+}
+
+type valueOperand struct {
+	value types.AttributeValue
+}
+
+func (o valueOperand) eval(item) (types.AttributeValue, bool) { return o.value, true }
+
+type pathOperand struct {
+	path path
+}
+
+func (o pathOperand) eval(i item) (types.AttributeValue, bool) { return o.path.get(i) }
+
+type sizeOperand struct {
+	path path
+}
+
+func (o sizeOperand) eval(i item) (types.AttributeValue, bool) {
+	av, ok := o.path.get(i)
+	if !ok {
+		return nil, false
+	}
+	var n int
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		n = len(v.Value)
+	case *types.AttributeValueMemberB:
+		n = len(v.Value)
+	case *types.AttributeValueMemberL:
+		n = len(v.Value)
+	case *types.AttributeValueMemberM:
+		n = len(v.Value)
+	default:
+		if !isSet(av) {
+			return nil, false
+		}
+		n = len(setElements(av))
+	}
+	return &types.AttributeValueMemberN{Value: strconv.Itoa(n)}, true
+}
+
+// condition is a boolean expression evaluated against the item.
+type condition interface {
+	eval(i item) bool
+}
+
+type andCondition struct{ left, right condition }
+
+func (c andCondition) eval(i item) bool { return c.left.eval(i) && c.right.eval(i) }
+
+type orCondition struct{ left, right condition }
+
+func (c orCondition) eval(i item) bool { return c.left.eval(i) || c.right.eval(i) }
+
+type notCondition struct{ cond condition }
+
+func (c notCondition) eval(i item) bool { return !c.cond.eval(i) }
+
+type compareCondition struct {
+	op          string
+	left, right operand
+}
+
+func (c compareCondition) eval(i item) bool {
+	l, lok := c.left.eval(i)
+	r, rok := c.right.eval(i)
+	if c.op == "<>" {
+		return !lok || !rok || !equal(l, r)
+	}
+	if !lok || !rok {
+		return false
+	}
+	if c.op == "=" {
+		return equal(l, r)
+	}
+	cmp, ok := compare(l, r)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+type betweenCondition struct {
+	value, low, high operand
+}
+
+func (c betweenCondition) eval(i item) bool {
+	v, ok1 := c.value.eval(i)
+	lo, ok2 := c.low.eval(i)
+	hi, ok3 := c.high.eval(i)
+	if !ok1 || !ok2 || !ok3 {
+		return false
+	}
+	c1, ok1 := compare(v, lo)
+	c2, ok2 := compare(v, hi)
+	return ok1 && ok2 && c1 >= 0 && c2 <= 0
+}
+
+type inCondition struct {
+	value operand
+	list  []operand
+}
+
+func (c inCondition) eval(i item) bool {
+	v, ok := c.value.eval(i)
+	if !ok {
+		return false
+	}
+	for _, o := range c.list {
+		if e, ok := o.eval(i); ok && equal(v, e) {
+			return true
+		}
+	}
+	return false
+}
+
+type functionCondition struct {
+	name string
+	path path
+	arg  operand
+}
+
+func (c functionCondition) eval(i item) bool {
+	av, ok := c.path.get(i)
+	switch c.name {
+	case "attribute_exists":
+		return ok
+	case "attribute_not_exists":
+		return !ok
+	}
+	if !ok {
+		return false
+	}
+	arg, ok := c.arg.eval(i)
+	if !ok {
+		return false
+	}
+	switch c.name {
+	case "attribute_type":
+		s, ok := arg.(*types.AttributeValueMemberS)
+		return ok && s.Value == typeOf(av)
+	case "begins_with":
+		switch v := av.(type) {
+		case *types.AttributeValueMemberS:
+			prefix, ok := arg.(*types.AttributeValueMemberS)
+			return ok && strings.HasPrefix(v.Value, prefix.Value)
+		case *types.AttributeValueMemberB:
+			prefix, ok := arg.(*types.AttributeValueMemberB)
+			return ok && strings.HasPrefix(string(v.Value), string(prefix.Value))
+		}
+		return false
+	default: // contains
+		switch v := av.(type) {
+		case *types.AttributeValueMemberS:
+			sub, ok := arg.(*types.AttributeValueMemberS)
+			return ok && strings.Contains(v.Value, sub.Value)
+		case *types.AttributeValueMemberB:
+			sub, ok := arg.(*types.AttributeValueMemberB)
+			return ok && strings.Contains(string(v.Value), string(sub.Value))
+		case *types.AttributeValueMemberL:
+			return containsElement(v.Value, arg)
+		}
+		return isSet(av) && containsElement(setElements(av), arg)
+	}
+}
+
+// updateValue is the right hand side of SET action.
+type updateValue interface {
+	eval(i item) (types.AttributeValue, error)
+}
+
+type operandValue struct {
+	operand operand
+}
+
+func (v operandValue) eval(i item) (types.AttributeValue, error) {
+	av, ok := v.operand.eval(i)
+	if !ok {
+		return nil, fmt.Errorf("The provided expression refers to an attribute that does not exist in the item")
+	}
+	return copyValue(av), nil
+}
+
+type arithmeticValue struct {
+	op          string
+	left, right updateValue
+}
+
+func (v arithmeticValue) eval(i item) (types.AttributeValue, error) {
+	l, err := v.left.eval(i)
+	if err != nil {
+		return nil, err
+	}
+	r, err := v.right.eval(i)
+	if err != nil {
+		return nil, err
+	}
+	ln, lok := l.(*types.AttributeValueMemberN)
+	rn, rok := r.(*types.AttributeValueMemberN)
+	if !lok || !rok {
+		return nil, fmt.Errorf("An operand in the update expression has an incorrect data type")
+	}
+	x, _ := parseNumber(ln.Value)
+	y, _ := parseNumber(rn.Value)
+	if v.op == "+" {
+		return &types.AttributeValueMemberN{Value: formatNumber(x.Add(x, y))}, nil
+	}
+	return &types.AttributeValueMemberN{Value: formatNumber(x.Sub(x, y))}, nil
+}
+
+type ifNotExistsValue struct {
+	path  path
+	value updateValue
+}
+
+func (v ifNotExistsValue) eval(i item) (types.AttributeValue, error) {
+	if av, ok := v.path.get(i); ok {
+		return copyValue(av), nil
+	}
+	return v.value.eval(i)
+}
+
+type listAppendValue struct {
+	left, right updateValue
+}
+
+func (v listAppendValue) eval(i item) (types.AttributeValue, error) {
+	l, err := v.left.eval(i)
+	if err != nil {
+		return nil, err
+	}
+	r, err := v.right.eval(i)
+	if err != nil {
+		return nil, err
+	}
+	ll, lok := l.(*types.AttributeValueMemberL)
+	rl, rok := r.(*types.AttributeValueMemberL)
+	if !lok || !rok {
+		return nil, fmt.Errorf("An operand in the update expression has an incorrect data type")
+	}
+	return &types.AttributeValueMemberL{Value: append(append([]types.AttributeValue{}, ll.Value...), rl.Value...)}, nil
+}
+
+type updateAction struct {
+	kind  string
+	path  path
+	value updateValue
+	// operand is a value of ADD and DELETE actions.
+	operand operand
+}
+
+type update []updateAction
+
+// apply evaluates all the actions against the old item and applies them to the copy of it.
+func (u update) apply(old item) (item, error) {
+	values := make([]types.AttributeValue, len(u))
+	for n, a := range u {
+		switch a.kind {
+		case "SET":
+			av, err := a.value.eval(old)
+			if err != nil {
+				return nil, err
+			}
+			values[n] = av
+		case "ADD", "DELETE":
+			values[n], _ = a.operand.eval(old)
+		}
+	}
+	i := copyItem(old)
+	// Remove list elements from the end so indexes stay valid.
+	removals := make([]int, 0)
+	for n, a := range u {
+		switch a.kind {
+		case "SET":
+			if err := a.path.set(i, values[n]); err != nil {
+				return nil, err
+			}
+		case "REMOVE":
+			removals = append(removals, n)
+		case "ADD":
+			if err := add(i, a.path, values[n]); err != nil {
+				return nil, err
+			}
+		case "DELETE":
+			if err := deleteFromSet(i, a.path, values[n]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.SliceStable(removals, func(x, y int) bool {
+		px, py := u[removals[x]].path, u[removals[y]].path
+		ex, ey := px[len(px)-1], py[len(py)-1]
+		return ex.isIndex && ey.isIndex && ex.index > ey.index
+	})
+	for _, n := range removals {
+		u[n].path.remove(i)
+	}
+	return i, nil
+}
+
+func add(i item, p path, av types.AttributeValue) error {
+	cur, ok := p.get(i)
+	if !ok {
+		if _, isNumber := av.(*types.AttributeValueMemberN); !isNumber && !isSet(av) {
+			return fmt.Errorf("Incorrect operand type for operator or function; operator: ADD, operand type: %s", typeOf(av))
+		}
+		return p.set(i, copyValue(av))
+	}
+	if n, ok := cur.(*types.AttributeValueMemberN); ok {
+		m, ok := av.(*types.AttributeValueMemberN)
+		if !ok {
+			return fmt.Errorf("An operand in the update expression has an incorrect data type")
+		}
+		x, _ := parseNumber(n.Value)
+		y, _ := parseNumber(m.Value)
+		return p.set(i, &types.AttributeValueMemberN{Value: formatNumber(x.Add(x, y))})
+	}
+	if !isSet(cur) || typeOf(cur) != typeOf(av) {
+		return fmt.Errorf("An operand in the update expression has an incorrect data type")
+	}
+	elems := setElements(cur)
+	for _, e := range setElements(av) {
+		if !containsElement(elems, e) {
+			elems = append(elems, e)
+		}
+	}
+	return p.set(i, newSet(typeOf(cur), elems))
+}
+
+func deleteFromSet(i item, p path, av types.AttributeValue) error {
+	cur, ok := p.get(i)
+	if !ok {
+		return nil
+	}
+	if !isSet(cur) || typeOf(cur) != typeOf(av) {
+		return fmt.Errorf("An operand in the update expression has an incorrect data type")
+	}
+	var elems []types.AttributeValue
+	remove := setElements(av)
+	for _, e := range setElements(cur) {
+		if !containsElement(remove, e) {
+			elems = append(elems, e)
+		}
+	}
+	if len(elems) == 0 {
+		p.remove(i)
+		return nil
+	}
+	return p.set(i, newSet(typeOf(cur), elems))
+}
+
+// project returns the copy of the item with only given document paths.
+func project(i item, paths []path) item {
+	if len(paths) == 0 {
+		return copyItem(i)
+	}
+	out := make(item)
+	for _, p := range paths {
+		av, ok := p.get(i)
+		if !ok {
+			continue
+		}
+		put(out, p, copyValue(av))
+	}
+	return out
+}
+
+func put(out item, p path, av types.AttributeValue) {
+	var cur types.AttributeValue = &types.AttributeValueMemberM{Value: out}
+	for n, e := range p {
+		last := n == len(p)-1
+		var next types.AttributeValue
+		if !last {
+			if p[n+1].isIndex {
+				next = &types.AttributeValueMemberL{}
+			} else {
+				next = &types.AttributeValueMemberM{Value: make(item)}
+			}
+		} else {
+			next = av
+		}
+		switch v := cur.(type) {
+		case *types.AttributeValueMemberM:
+			if existing, ok := v.Value[e.name]; ok && !last {
+				next = existing
+			} else {
+				v.Value[e.name] = next
+			}
+		case *types.AttributeValueMemberL:
+			v.Value = append(v.Value, next)
+		}
+		cur = next
+	}
+}
+
+// parser turns the expression into its syntax tree, resolving attribute name and value placeholders.
+type parser struct {
+	kind   string
+	toks   []token
+	pos    int
+	names  map[string]string
+	values map[string]types.AttributeValue
+	used   map[string]bool
+}
+
+func newParser(names map[string]string, values map[string]types.AttributeValue) *parser {
+	return &parser{names: names, values: values, used: make(map[string]bool)}
+}
+
+// unused returns error if some placeholders weren't used by any of the parsed expressions.
+func (p *parser) unused() error {
+	var names, values []string
+	for name := range p.names {
+		if !p.used[name] {
+			names = append(names, name)
+		}
+	}
+	for name := range p.values {
+		if !p.used[name] {
+			values = append(values, name)
+		}
+	}
+	sort.Strings(names)
+	sort.Strings(values)
+	if len(names) > 0 {
+		return fmt.Errorf("Value provided in ExpressionAttributeNames unused in expressions: keys: {%s}", strings.Join(names, ", "))
+	}
+	if len(values) > 0 {
+		return fmt.Errorf("Value provided in ExpressionAttributeValues unused in expressions: keys: {%s}", strings.Join(values, ", "))
+	}
+	return nil
+}
+
+func (p *parser) reset(kind, expr string) error {
+	toks, err := tokenize(expr)
+	if err != nil {
+		return fmt.Errorf("Invalid %s: %v", kind, err)
+	}
+	p.kind, p.toks, p.pos = kind, toks, 0
+	return nil
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Invalid %s: %s", p.kind, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) syntaxError() error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return p.errorf("Syntax error; token: <EOF>")
+	}
+	return p.errorf("Syntax error; token: %q", t.text)
+}
+
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokPunct && t.text == text) || (t.kind == tokIdent && strings.EqualFold(t.text, text)) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.syntaxError()
+	}
+	return nil
+}
+
+func (p *parser) end() error {
+	if p.peek().kind != tokEOF {
+		return p.syntaxError()
+	}
+	return nil
+}
+
+func (p *parser) parseCondition(kind, expr string) (condition, error) {
+	if err := p.reset(kind, expr); err != nil {
+		return nil, err
+	}
+	c, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	return c, p.end()
+}
+
+func (p *parser) or() (condition, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("OR") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orCondition{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (condition, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("AND") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = andCondition{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (condition, error) {
+	if p.accept("NOT") {
+		c, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{c}, nil
+	}
+	return p.primary()
+}
+
+var conditionFunctions = map[string]int{
+	"attribute_exists":     1,
+	"attribute_not_exists": 1,
+	"attribute_type":       2,
+	"begins_with":          2,
+	"contains":             2,
+}
+
+func (p *parser) primary() (condition, error) {
+	if p.accept("(") {
+		c, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expect(")")
+	}
+	if t := p.peek(); t.kind == tokIdent && p.toks[p.pos+1].text == "(" {
+		if arity, ok := conditionFunctions[strings.ToLower(t.text)]; ok {
+			p.pos += 2
+			return p.function(strings.ToLower(t.text), arity)
+		}
+	}
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	switch t := p.peek(); {
+	case t.kind == tokPunct && (t.text == "=" || t.text == "<>" || t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="):
+		p.pos++
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return compareCondition{op: t.text, left: left, right: right}, nil
+	case p.accept("BETWEEN"):
+		low, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("AND"); err != nil {
+			return nil, err
+		}
+		high, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return betweenCondition{value: left, low: low, high: high}, nil
+	case p.accept("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		c := inCondition{value: left}
+		for {
+			o, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			c.list = append(c.list, o)
+			if !p.accept(",") {
+				break
+			}
+		}
+		return c, p.expect(")")
+	default:
+		return nil, p.syntaxError()
+	}
+}
+
+func (p *parser) function(name string, arity int) (condition, error) {
+	docPath, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	c := functionCondition{name: name, path: docPath}
+	if arity == 2 {
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		if c.arg, err = p.operand(); err != nil {
+			return nil, err
+		}
+	}
+	return c, p.expect(")")
+}
+
+func (p *parser) operand() (operand, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokValue:
+		p.pos++
+		av, err := p.value(t.text)
+		if err != nil {
+			return nil, err
+		}
+		return valueOperand{av}, nil
+	case t.kind == tokIdent && strings.EqualFold(t.text, "size") && p.toks[p.pos+1].text == "(":
+		p.pos += 2
+		docPath, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		return sizeOperand{docPath}, p.expect(")")
+	default:
+		docPath, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		return pathOperand{docPath}, nil
+	}
+}
+
+func (p *parser) value(placeholder string) (types.AttributeValue, error) {
+	av, ok := p.values[placeholder]
+	if !ok {
+		return nil, p.errorf("An expression attribute value used in expression is not defined; attribute value: %s", placeholder)
+	}
+	p.used[placeholder] = true
+	return av, nil
+}
+
+func (p *parser) path() (path, error) {
+	var docPath path
+	elem, err := p.pathName()
+	if err != nil {
+		return nil, err
+	}
+	docPath = append(docPath, elem)
+	for {
+		switch {
+		case p.accept("."):
+			elem, err := p.pathName()
+			if err != nil {
+				return nil, err
+			}
+			docPath = append(docPath, elem)
+		case p.accept("["):
+			t := p.next()
+			if t.kind != tokNumber {
+				return nil, p.syntaxError()
+			}
+			index, _ := strconv.Atoi(t.text)
+			docPath = append(docPath, pathElem{index: index, isIndex: true})
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		default:
+			return docPath, nil
+		}
+	}
+}
+
+func (p *parser) pathName() (pathElem, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokIdent:
+		p.pos++
+		return pathElem{name: t.text}, nil
+	case tokName:
+		p.pos++
+		name, ok := p.names[t.text]
+		if !ok {
+			return pathElem{}, p.errorf("An expression attribute name used in the document path is not defined; attribute name: %s", t.text)
+		}
+		p.used[t.text] = true
+		return pathElem{name: name}, nil
+	}
+	return pathElem{}, p.syntaxError()
+}
+
+func (p *parser) parseProjection(expr string) ([]path, error) {
+	if err := p.reset("ProjectionExpression", expr); err != nil {
+		return nil, err
+	}
+	var paths []path
+	for {
+		docPath, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, docPath)
+		if !p.accept(",") {
+			break
+		}
+	}
+	return paths, p.end()
+}
+
+var updateClauses = []string{"SET", "REMOVE", "ADD", "DELETE"}
+
+func (p *parser) parseUpdate(expr string) (update, error) {
+	if err := p.reset("UpdateExpression", expr); err != nil {
+		return nil, err
+	}
+	var u update
+	seen := make(map[string]bool)
+	for p.peek().kind != tokEOF {
+		var clause string
+		for _, c := range updateClauses {
+			if p.accept(c) {
+				clause = c
+			}
+		}
+		if clause == "" {
+			return nil, p.syntaxError()
+		}
+		if seen[clause] {
+			return nil, p.errorf("The %q section can only be used once in an update expression", clause)
+		}
+		seen[clause] = true
+		for {
+			a, err := p.action(clause)
+			if err != nil {
+				return nil, err
+			}
+			u = append(u, a)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if len(u) == 0 {
+		return nil, p.syntaxError()
+	}
+	return u, p.checkOverlap(u)
+}
+
+func (p *parser) checkOverlap(u update) error {
+	for x := range u {
+		for y := x + 1; y < len(u); y++ {
+			a, b := u[x].path, u[y].path
+			n := len(a)
+			if len(b) < n {
+				n = len(b)
+			}
+			if a[:n].String() == b[:n].String() {
+				return p.errorf("Two document paths overlap with each other; must remove or rewrite one of these paths; path one: [%s], path two: [%s]", a, b)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *parser) action(clause string) (updateAction, error) {
+	docPath, err := p.path()
+	if err != nil {
+		return updateAction{}, err
+	}
+	a := updateAction{kind: clause, path: docPath}
+	switch clause {
+	case "SET":
+		if err := p.expect("="); err != nil {
+			return a, err
+		}
+		a.value, err = p.setValue()
+	case "ADD", "DELETE":
+		t := p.next()
+		if t.kind != tokValue {
+			p.pos--
+			return a, p.syntaxError()
+		}
+		var av types.AttributeValue
+		av, err = p.value(t.text)
+		a.operand = valueOperand{av}
+	}
+	return a, err
+}
+
+func (p *parser) setValue() (updateValue, error) {
+	left, err := p.setOperand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"+", "-"} {
+		if p.accept(op) {
+			right, err := p.setOperand()
+			if err != nil {
+				return nil, err
+			}
+			return arithmeticValue{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) setOperand() (updateValue, error) {
+	t := p.peek()
+	if t.kind == tokIdent && p.toks[p.pos+1].text == "(" {
+		switch strings.ToLower(t.text) {
+		case "if_not_exists":
+			p.pos += 2
+			docPath, err := p.path()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			v, err := p.setOperand()
+			if err != nil {
+				return nil, err
+			}
+			return ifNotExistsValue{path: docPath, value: v}, p.expect(")")
+		case "list_append":
+			p.pos += 2
+			left, err := p.setOperand()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			right, err := p.setOperand()
+			if err != nil {
+				return nil, err
+			}
+			return listAppendValue{left: left, right: right}, p.expect(")")
+		}
+		return nil, p.errorf("Invalid function name; function: %s", t.text)
+	}
+	o, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := o.(sizeOperand); ok {
+		return nil, p.errorf("The function is not allowed in an update expression; function: size")
+	}
+	return operandValue{o}, nil
+}
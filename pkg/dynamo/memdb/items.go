@@ -0,0 +1,739 @@
+package memdb
+
+import (
+	"math"
+	"sort"
+
+	"dynamodb-with-go/pkg/dynamo/ddbjson"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxPageSize is the amount of data Query and Scan read before returning the page.
+const maxPageSize = 1 << 20
+
+type expressionInput struct {
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues ddbjson.Item
+}
+
+func (in expressionInput) parser() *parser {
+	return newParser(in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+}
+
+// condition parses optional condition expression of given kind.
+func (p *parser) condition(kind string, expr *string) (condition, error) {
+	if expr == nil {
+		return nil, nil
+	}
+	c, err := p.parseCondition(kind, *expr)
+	if err != nil {
+		return nil, validationError("%v", err)
+	}
+	return c, nil
+}
+
+func (p *parser) projection(expr *string) ([]path, error) {
+	if expr == nil {
+		return nil, nil
+	}
+	paths, err := p.parseProjection(*expr)
+	if err != nil {
+		return nil, validationError("%v", err)
+	}
+	return paths, nil
+}
+
+func (p *parser) update(expr *string) (update, error) {
+	if expr == nil {
+		return nil, validationError("UpdateExpression must be specified")
+	}
+	u, err := p.parseUpdate(*expr)
+	if err != nil {
+		return nil, validationError("%v", err)
+	}
+	return u, nil
+}
+
+func (p *parser) done() error {
+	if err := p.unused(); err != nil {
+		return validationError("%v", err)
+	}
+	return nil
+}
+
+type consumedCapacity struct {
+	TableName     string
+	CapacityUnits float64
+}
+
+// capacity returns consumed capacity if it was requested.
+func capacity(mode types.ReturnConsumedCapacity, tableName string, units float64) *consumedCapacity {
+	if mode == "" || mode == types.ReturnConsumedCapacityNone {
+		return nil
+	}
+	return &consumedCapacity{TableName: tableName, CapacityUnits: units}
+}
+
+func readUnits(bytes int, consistent bool) float64 {
+	units := math.Ceil(float64(bytes) / 4096)
+	if units == 0 {
+		units = 1
+	}
+	if !consistent {
+		units /= 2
+	}
+	return units
+}
+
+func writeUnits(bytes int) float64 {
+	units := math.Ceil(float64(bytes) / 1024)
+	if units == 0 {
+		units = 1
+	}
+	return units
+}
+
+type putItemInput struct {
+	expressionInput
+	TableName              *string
+	Item                   ddbjson.Item
+	ConditionExpression    *string
+	ReturnValues           types.ReturnValue
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+}
+
+func (db *DB) putItem(body []byte) (interface{}, error) {
+	var in putItemInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if in.ReturnValues != "" && in.ReturnValues != types.ReturnValueNone && in.ReturnValues != types.ReturnValueAllOld {
+		return nil, validationError("ReturnValues can only be ALL_OLD or NONE")
+	}
+	p := in.parser()
+	cond, err := p.condition("ConditionExpression", in.ConditionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.done(); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.checkItem(in.Item); err != nil {
+		return nil, err
+	}
+	old := t.items[t.id(in.Item)]
+	if cond != nil && !cond.eval(old) {
+		return nil, conditionalCheckFailed()
+	}
+	t.items[t.id(in.Item)] = copyItem(in.Item)
+	out := map[string]interface{}{
+		"ConsumedCapacity": capacity(in.ReturnConsumedCapacity, aws.ToString(in.TableName), writeUnits(itemSize(in.Item))),
+	}
+	if in.ReturnValues == types.ReturnValueAllOld && old != nil {
+		out["Attributes"] = ddbjson.Item(old)
+	}
+	return out, nil
+}
+
+type getItemInput struct {
+	TableName                *string
+	Key                      ddbjson.Item
+	ConsistentRead           *bool
+	ProjectionExpression     *string
+	ExpressionAttributeNames map[string]string
+	ReturnConsumedCapacity   types.ReturnConsumedCapacity
+}
+
+func (db *DB) getItem(body []byte) (interface{}, error) {
+	var in getItemInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	p := newParser(in.ExpressionAttributeNames, nil)
+	paths, err := p.projection(in.ProjectionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.done(); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.checkKey(in.Key); err != nil {
+		return nil, err
+	}
+	i, ok := t.items[t.id(in.Key)]
+	out := map[string]interface{}{
+		"ConsumedCapacity": capacity(in.ReturnConsumedCapacity, aws.ToString(in.TableName), readUnits(itemSize(i), aws.ToBool(in.ConsistentRead))),
+	}
+	if ok {
+		out["Item"] = ddbjson.Item(project(i, paths))
+	}
+	return out, nil
+}
+
+type updateItemInput struct {
+	expressionInput
+	TableName              *string
+	Key                    ddbjson.Item
+	UpdateExpression       *string
+	ConditionExpression    *string
+	ReturnValues           types.ReturnValue
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+}
+
+func (db *DB) updateItem(body []byte) (interface{}, error) {
+	var in updateItemInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	p := in.parser()
+	u, err := p.update(in.UpdateExpression)
+	if err != nil {
+		return nil, err
+	}
+	cond, err := p.condition("ConditionExpression", in.ConditionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.done(); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	old, updated, err := t.update(in.Key, u, cond)
+	if err != nil {
+		return nil, err
+	}
+	t.items[t.id(updated)] = updated
+	out := map[string]interface{}{
+		"ConsumedCapacity": capacity(in.ReturnConsumedCapacity, aws.ToString(in.TableName), writeUnits(itemSize(updated))),
+	}
+	var attrs item
+	switch in.ReturnValues {
+	case types.ReturnValueAllOld:
+		attrs = old
+	case types.ReturnValueAllNew:
+		attrs = updated
+	case types.ReturnValueUpdatedOld:
+		attrs = u.touched(old)
+	case types.ReturnValueUpdatedNew:
+		attrs = u.touched(updated)
+	}
+	if len(attrs) > 0 {
+		out["Attributes"] = ddbjson.Item(copyItem(attrs))
+	}
+	return out, nil
+}
+
+// update applies the update expression to the item with the key, checking the condition first.
+// It returns the item before and after the update.
+func (t *table) update(key item, u update, cond condition) (old, updated item, err error) {
+	if err := t.checkKey(key); err != nil {
+		return nil, nil, err
+	}
+	for _, a := range u {
+		if _, ok := key[a.path[0].name]; ok {
+			return nil, nil, validationError("One or more parameter values were invalid: Cannot update attribute %s. This attribute is part of the key", a.path[0].name)
+		}
+	}
+	old = t.items[t.id(key)]
+	if cond != nil && !cond.eval(old) {
+		return old, nil, conditionalCheckFailed()
+	}
+	base := old
+	if base == nil {
+		base = key
+	}
+	updated, err = u.apply(base)
+	if err != nil {
+		return nil, nil, validationError("%v", err)
+	}
+	if err := t.checkItem(updated); err != nil {
+		return nil, nil, err
+	}
+	return old, updated, nil
+}
+
+// touched returns top level attributes of the item modified by the update.
+func (u update) touched(i item) item {
+	out := make(item)
+	for _, a := range u {
+		if av, ok := i[a.path[0].name]; ok {
+			out[a.path[0].name] = av
+		}
+	}
+	return out
+}
+
+type deleteItemInput struct {
+	expressionInput
+	TableName              *string
+	Key                    ddbjson.Item
+	ConditionExpression    *string
+	ReturnValues           types.ReturnValue
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+}
+
+func (db *DB) deleteItem(body []byte) (interface{}, error) {
+	var in deleteItemInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	p := in.parser()
+	cond, err := p.condition("ConditionExpression", in.ConditionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.done(); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.checkKey(in.Key); err != nil {
+		return nil, err
+	}
+	old := t.items[t.id(in.Key)]
+	if cond != nil && !cond.eval(old) {
+		return nil, conditionalCheckFailed()
+	}
+	delete(t.items, t.id(in.Key))
+	out := map[string]interface{}{
+		"ConsumedCapacity": capacity(in.ReturnConsumedCapacity, aws.ToString(in.TableName), writeUnits(itemSize(old))),
+	}
+	if in.ReturnValues == types.ReturnValueAllOld && old != nil {
+		out["Attributes"] = ddbjson.Item(old)
+	}
+	return out, nil
+}
+
+type readInput struct {
+	expressionInput
+	TableName              *string
+	IndexName              *string
+	FilterExpression       *string
+	ProjectionExpression   *string
+	ExclusiveStartKey      ddbjson.Item
+	Limit                  *int32
+	ConsistentRead         *bool
+	Select                 types.Select
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+}
+
+type queryInput struct {
+	readInput
+	KeyConditionExpression *string
+	ScanIndexForward       *bool
+}
+
+func (db *DB) query(body []byte) (interface{}, error) {
+	var in queryInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	p := in.parser()
+	keyCond, err := p.condition("KeyConditionExpression", in.KeyConditionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if keyCond == nil {
+		return nil, validationError("Either the KeyConditions or KeyConditionExpression parameter must be specified in the request.")
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, ix, err := db.readTarget(in.readInput)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKeyCondition(keyCond, ix); err != nil {
+		return nil, err
+	}
+	order := t.order(ix)
+	if in.ScanIndexForward != nil && !*in.ScanIndexForward {
+		forward := order
+		order = func(a, b item) int { return -forward(a, b) }
+	}
+	var rows []item
+	for _, i := range t.rows(ix) {
+		if keyCond.eval(i) {
+			rows = append(rows, i)
+		}
+	}
+	return t.read(in.readInput, p, ix, rows, order)
+}
+
+// checkKeyCondition makes sure that key condition only consists of the hash key equality
+// and optional range key condition.
+func checkKeyCondition(c condition, ix index) error {
+	var parts []condition
+	var flatten func(c condition)
+	flatten = func(c condition) {
+		if and, ok := c.(andCondition); ok {
+			flatten(and.left)
+			flatten(and.right)
+			return
+		}
+		parts = append(parts, c)
+	}
+	flatten(c)
+	attr := func(o operand) string {
+		if p, ok := o.(pathOperand); ok && len(p.path) == 1 {
+			return p.path[0].name
+		}
+		return ""
+	}
+	var hash, rng int
+	for _, part := range parts {
+		var name string
+		switch c := part.(type) {
+		case compareCondition:
+			if _, ok := c.right.(valueOperand); !ok || c.op == "<>" {
+				return validationError("Query key condition not supported")
+			}
+			name = attr(c.left)
+			if name == ix.hash && c.op != "=" {
+				return validationError("Query key condition not supported")
+			}
+		case betweenCondition:
+			name = attr(c.value)
+		case functionCondition:
+			if c.name == "begins_with" && len(c.path) == 1 {
+				name = c.path[0].name
+			}
+		}
+		switch {
+		case name == ix.hash && name != "":
+			hash++
+		case name == ix.rng && name != "" && name != ix.hash:
+			rng++
+		default:
+			return validationError("Query condition missed key schema element: %s", ix.hash)
+		}
+	}
+	if hash != 1 {
+		return validationError("Query condition missed key schema element: %s", ix.hash)
+	}
+	if rng > 1 {
+		return validationError("KeyConditionExpressions must only contain one condition per key")
+	}
+	return nil
+}
+
+type scanInput struct {
+	readInput
+	Segment       *int32
+	TotalSegments *int32
+}
+
+func (db *DB) scan(body []byte) (interface{}, error) {
+	var in scanInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if (in.Segment == nil) != (in.TotalSegments == nil) {
+		return nil, validationError("The TotalSegments parameter is required but was not present in the request when Segment parameter is present")
+	}
+	segment, total := aws.ToInt32(in.Segment), aws.ToInt32(in.TotalSegments)
+	if in.TotalSegments != nil && (total < 1 || total > 1000000 || segment < 0 || segment >= total) {
+		return nil, validationError("The Segment parameter is zero-based and must be less than parameter TotalSegments: Segment: %d is not less than TotalSegments: %d", segment, total)
+	}
+	p := in.parser()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, ix, err := db.readTarget(in.readInput)
+	if err != nil {
+		return nil, err
+	}
+	var rows []item
+	for _, i := range t.rows(ix) {
+		if total == 0 || int32(partitionHash(i, ix.hash)%uint32(total)) == segment {
+			rows = append(rows, i)
+		}
+	}
+	return t.read(in.readInput, p, ix, rows, t.order(ix))
+}
+
+func (db *DB) readTarget(in readInput) (*table, index, error) {
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, index{}, err
+	}
+	ix, err := t.index(aws.ToString(in.IndexName))
+	if err != nil {
+		return nil, index{}, err
+	}
+	if ix.global && aws.ToBool(in.ConsistentRead) {
+		return nil, index{}, validationError("Consistent reads are not supported on global secondary indexes")
+	}
+	return t, ix, nil
+}
+
+// read pages through the rows sorted in the given order, applying filter, projection and limits.
+func (t *table) read(in readInput, p *parser, ix index, rows []item, order func(a, b item) int) (interface{}, error) {
+	filter, err := p.condition("FilterExpression", in.FilterExpression)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := p.projection(in.ProjectionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.done(); err != nil {
+		return nil, err
+	}
+	if in.Limit != nil && *in.Limit < 1 {
+		return nil, validationError("1 validation error detected: Value '%d' at 'limit' failed to satisfy constraint: Member must have value greater than or equal to 1", *in.Limit)
+	}
+	if in.Select == types.SelectSpecificAttributes && paths == nil {
+		return nil, validationError("SPECIFIC_ATTRIBUTES requires ProjectionExpression")
+	}
+	if in.Select != "" && in.Select != types.SelectSpecificAttributes && paths != nil {
+		return nil, validationError("Cannot specify the ProjectionExpression when choosing to get %s", in.Select)
+	}
+	sort.Slice(rows, func(x, y int) bool { return order(rows[x], rows[y]) < 0 })
+	if in.ExclusiveStartKey != nil {
+		start := item(in.ExclusiveStartKey)
+		if err := t.checkKey(t.key(start)); err != nil {
+			return nil, validationError("The provided starting key is invalid: %v", err)
+		}
+		n := sort.Search(len(rows), func(n int) bool { return order(rows[n], start) > 0 })
+		rows = rows[n:]
+	}
+	items := make([]ddbjson.Item, 0)
+	var scanned, bytes int
+	var last item
+	for n, i := range rows {
+		if (in.Limit != nil && scanned == int(*in.Limit)) || bytes >= maxPageSize {
+			break
+		}
+		scanned++
+		bytes += itemSize(i)
+		if n < len(rows)-1 {
+			last = i
+		} else {
+			last = nil
+		}
+		if filter != nil && !filter.eval(i) {
+			continue
+		}
+		switch {
+		case paths != nil:
+			items = append(items, project(t.projectIndex(ix, i), paths))
+		case in.Select == types.SelectAllAttributes:
+			items = append(items, copyItem(i))
+		default:
+			items = append(items, t.projectIndex(ix, i))
+		}
+	}
+	out := map[string]interface{}{
+		"Count":            len(items),
+		"ScannedCount":     scanned,
+		"ConsumedCapacity": capacity(in.ReturnConsumedCapacity, aws.ToString(in.TableName), readUnits(bytes, aws.ToBool(in.ConsistentRead))),
+	}
+	if in.Select != types.SelectCount {
+		out["Items"] = items
+	}
+	if last != nil {
+		out["LastEvaluatedKey"] = ddbjson.Item(t.indexKey(ix, last))
+	}
+	return out, nil
+}
+
+type transactWriteItem struct {
+	ConditionCheck *transactAction
+	Put            *transactAction
+	Delete         *transactAction
+	Update         *transactAction
+}
+
+type transactAction struct {
+	expressionInput
+	TableName                           *string
+	Key                                 ddbjson.Item
+	Item                                ddbjson.Item
+	ConditionExpression                 *string
+	UpdateExpression                    *string
+	ReturnValuesOnConditionCheckFailure types.ReturnValuesOnConditionCheckFailure
+}
+
+type transactWriteItemsInput struct {
+	TransactItems          []transactWriteItem
+	ClientRequestToken     *string
+	ReturnConsumedCapacity types.ReturnConsumedCapacity
+}
+
+type cancellationReason struct {
+	Code    string
+	Message string       `json:",omitempty"`
+	Item    ddbjson.Item `json:",omitempty"`
+}
+
+// change is what a transaction does to to a single item.
+type change struct {
+	table  *table
+	id     string
+	item   item
+	delete bool
+}
+
+// transactWriteItems checks all the conditions first and only then applies all the changes,
+// so transaction is either applied completely or not at all.
+func (db *DB) transactWriteItems(body []byte) (interface{}, error) {
+	var in transactWriteItemsInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if len(in.TransactItems) == 0 || len(in.TransactItems) > 25 {
+		return nil, validationError("1 validation error detected: Value at 'transactItems' failed to satisfy constraint: Member must have length less than or equal to 25 and greater than or equal to 1")
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var changes []change
+	reasons := make([]cancellationReason, len(in.TransactItems))
+	cancelled := false
+	seen := make(map[string]bool)
+	units := make(map[string]float64)
+	for n, ti := range in.TransactItems {
+		w, old, err := db.transactWrite(ti)
+		if err, ok := err.(*apiError); ok && err.code == "ConditionalCheckFailedException" {
+			reasons[n] = cancellationReason{Code: "ConditionalCheckFailed", Message: err.message}
+			if action := ti.action(); action.ReturnValuesOnConditionCheckFailure == types.ReturnValuesOnConditionCheckFailureAllOld && old != nil {
+				reasons[n].Item = ddbjson.Item(copyItem(old))
+			}
+			cancelled = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		key := aws.ToString(w.table.desc.TableName) + "/" + w.id
+		if seen[key] {
+			return nil, validationError("Transaction request cannot include multiple operations on one item")
+		}
+		seen[key] = true
+		reasons[n] = cancellationReason{Code: "None"}
+		units[aws.ToString(w.table.desc.TableName)] += 2 * writeUnits(itemSize(w.item))
+		changes = append(changes, w)
+	}
+	if cancelled {
+		return nil, &apiError{code: "TransactionCanceledException", message: cancellationMessage(reasons), reasons: reasons}
+	}
+	for _, w := range changes {
+		switch {
+		case w.delete:
+			delete(w.table.items, w.id)
+		case w.item != nil:
+			w.table.items[w.id] = w.item
+		}
+	}
+	out := map[string]interface{}{}
+	if c := capacity(in.ReturnConsumedCapacity, "", 0); c != nil {
+		var consumed []*consumedCapacity
+		for name, u := range units {
+			consumed = append(consumed, &consumedCapacity{TableName: name, CapacityUnits: u})
+		}
+		out["ConsumedCapacity"] = consumed
+	}
+	return out, nil
+}
+
+func (ti transactWriteItem) action() *transactAction {
+	for _, a := range []*transactAction{ti.ConditionCheck, ti.Put, ti.Delete, ti.Update} {
+		if a != nil {
+			return a
+		}
+	}
+	return &transactAction{}
+}
+
+// transactWrite validates single transaction item and computes its change without applying it.
+func (db *DB) transactWrite(ti transactWriteItem) (change, item, error) {
+	count := 0
+	for _, a := range []*transactAction{ti.ConditionCheck, ti.Put, ti.Delete, ti.Update} {
+		if a != nil {
+			count++
+		}
+	}
+	if count != 1 {
+		return change{}, nil, validationError("TransactItems can only contain one of Check, Put, Update or Delete")
+	}
+	a := ti.action()
+	p := a.parser()
+	var u update
+	var err error
+	if ti.Update != nil {
+		if u, err = p.update(a.UpdateExpression); err != nil {
+			return change{}, nil, err
+		}
+	}
+	cond, err := p.condition("ConditionExpression", a.ConditionExpression)
+	if err != nil {
+		return change{}, nil, err
+	}
+	if ti.ConditionCheck != nil && cond == nil {
+		return change{}, nil, validationError("ConditionExpression must be specified for ConditionCheck")
+	}
+	if err := p.done(); err != nil {
+		return change{}, nil, err
+	}
+	t, err := db.table(a.TableName)
+	if err != nil {
+		return change{}, nil, err
+	}
+	key := item(a.Key)
+	if ti.Put != nil {
+		if err := t.checkItem(a.Item); err != nil {
+			return change{}, nil, err
+		}
+		key = t.key(a.Item)
+	} else if err := t.checkKey(key); err != nil {
+		return change{}, nil, err
+	}
+	w := change{table: t, id: t.id(key)}
+	old := t.items[w.id]
+	if ti.Update != nil {
+		old, w.item, err = t.update(key, u, cond)
+		return w, old, err
+	}
+	if cond != nil && !cond.eval(old) {
+		return w, old, conditionalCheckFailed()
+	}
+	switch {
+	case ti.Put != nil:
+		w.item = copyItem(a.Item)
+	case ti.Delete != nil:
+		w.delete = true
+	}
+	return w, old, nil
+}
+
+func cancellationMessage(reasons []cancellationReason) string {
+	msg := "Transaction cancelled, please refer cancellation reasons for specific reasons ["
+	for n, r := range reasons {
+		if n > 0 {
+			msg += ", "
+		}
+		msg += r.Code
+	}
+	return msg + "]"
+}
@@ -0,0 +1,190 @@
+// Package memdb is an in-memory implementation of the DynamoDB API. It speaks the same JSON protocol
+// as DynamoDB, so the regular SDK client talks to it, and supports everything the episodes need:
+// tables with local and global secondary indexes, item operations with expressions, batch reads
+// and writes, queries, scans and write transactions. It's meant for tests, not for production.
+package memdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	targetPrefix = "DynamoDB_20120810."
+	errorPrefix  = "com.amazonaws.dynamodb.v20120810#"
+)
+
+// DB is an in-memory DynamoDB. It implements http.Handler, serve it and point the client
+// to its address with the EndpointResolver.
+type DB struct {
+	mu       sync.Mutex
+	tables   map[string]*table
+	requests int64
+
+	batchLimit int
+}
+
+// New returns empty database.
+func New() *DB {
+	return &DB{tables: make(map[string]*table)}
+}
+
+type operation func(db *DB, body []byte) (interface{}, error)
+
+var operations = map[string]operation{
+	"CreateTable":               (*DB).createTable,
+	"DescribeTable":             (*DB).describeTable,
+	"UpdateTable":               (*DB).updateTable,
+	"DeleteTable":               (*DB).deleteTable,
+	"ListTables":                (*DB).listTables,
+	"UpdateTimeToLive":          (*DB).updateTimeToLive,
+	"DescribeTimeToLive":        (*DB).describeTimeToLive,
+	"UpdateContinuousBackups":   (*DB).updateContinuousBackups,
+	"DescribeContinuousBackups": (*DB).describeContinuousBackups,
+	"TagResource":               (*DB).tagResource,
+	"ListTagsOfResource":        (*DB).listTagsOfResource,
+	"PutItem":                   (*DB).putItem,
+	"GetItem":                   (*DB).getItem,
+	"UpdateItem":                (*DB).updateItem,
+	"DeleteItem":                (*DB).deleteItem,
+	"Query":                     (*DB).query,
+	"Scan":                      (*DB).scan,
+	"BatchGetItem":              (*DB).batchGetItem,
+	"BatchWriteItem":            (*DB).batchWriteItem,
+	"TransactWriteItems":        (*DB).transactWriteItems,
+}
+
+func (db *DB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := atomic.AddInt64(&db.requests, 1)
+	w.Header().Set("X-Amzn-Requestid", "memdb-"+strconv.FormatInt(id, 10))
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, &apiError{code: "SerializationException", message: err.Error()})
+		return
+	}
+	target := r.Header.Get("X-Amz-Target")
+	op, ok := operations[strings.TrimPrefix(target, targetPrefix)]
+	if !ok || !strings.HasPrefix(target, targetPrefix) {
+		writeError(w, &apiError{code: "UnknownOperationException", message: fmt.Sprintf("unsupported operation %q", target)})
+		return
+	}
+	out, err := op(db, body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	write(w, http.StatusOK, out)
+}
+
+// decode unmarshals the request, reporting malformed one the way DynamoDB does.
+func decode(body []byte, in interface{}) error {
+	if err := json.Unmarshal(body, in); err != nil {
+		return &apiError{code: "SerializationException", message: err.Error()}
+	}
+	return nil
+}
+
+func write(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(compact(v))
+	if err != nil {
+		status = http.StatusInternalServerError
+		b, _ = json.Marshal(map[string]string{
+			"__type":  errorPrefix + "InternalServerError",
+			"message": err.Error(),
+		})
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.Header().Set("X-Amz-Crc32", strconv.FormatUint(uint64(crc32.ChecksumIEEE(b)), 10))
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// compact drops null values the SDK types are full of, and turns timestamps into epoch seconds
+// as the protocol requires.
+func compact(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			if e = compact(e); e != nil {
+				out[k] = e
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(x))
+		for _, e := range x {
+			out = append(out, compact(e))
+		}
+		return out
+	case time.Time:
+		return float64(x.UnixNano()) / float64(time.Second)
+	case *time.Time:
+		if x == nil {
+			return nil
+		}
+		return compact(*x)
+	case string, bool, float64, json.Number, json.Marshaler, nil:
+		return v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		var generic interface{}
+		if err := json.Unmarshal(b, &generic); err != nil {
+			return v
+		}
+		return compact(generic)
+	}
+}
+
+type apiError struct {
+	code    string
+	message string
+	reasons []cancellationReason
+}
+
+func (e *apiError) Error() string {
+	return e.code + ": " + e.message
+}
+
+func validationError(format string, args ...interface{}) *apiError {
+	return &apiError{code: "ValidationException", message: fmt.Sprintf(format, args...)}
+}
+
+func resourceNotFound() *apiError {
+	return &apiError{code: "ResourceNotFoundException", message: "Requested resource not found"}
+}
+
+func conditionalCheckFailed() *apiError {
+	return &apiError{code: "ConditionalCheckFailedException", message: "The conditional request failed"}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e, ok := err.(*apiError)
+	if !ok {
+		e = &apiError{code: "InternalServerError", message: err.Error()}
+	}
+	status := http.StatusBadRequest
+	if e.code == "InternalServerError" {
+		status = http.StatusInternalServerError
+	}
+	body := map[string]interface{}{
+		"__type":  errorPrefix + e.code,
+		"message": e.message,
+	}
+	if e.reasons != nil {
+		body["Message"] = e.message
+		body["CancellationReasons"] = e.reasons
+	}
+	write(w, status, body)
+}
@@ -0,0 +1,422 @@
+package memdb_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"dynamodb-with-go/pkg/dynamo/memdb"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func setup(t *testing.T) (context.Context, *dynamodb.Client, func()) {
+	ctx := context.Background()
+	srv := httptest.NewServer(memdb.New())
+	db, err := dynamo.NewClient(ctx, dynamo.WithEndpoint(srv.URL))
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	_, err = db.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:   aws.String("Table"),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("sk"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("lsi_sk"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("gsi_pk"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("sk"), KeyType: types.KeyTypeRange},
+		},
+		LocalSecondaryIndexes: []types.LocalSecondaryIndex{{
+			IndexName: aws.String("ByNumber"),
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("lsi_sk"), KeyType: types.KeyTypeRange},
+			},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		}},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{{
+			IndexName: aws.String("ByGroup"),
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("gsi_pk"), KeyType: types.KeyTypeHash},
+			},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly},
+		}},
+	})
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return ctx, db, srv.Close
+}
+
+func s(v string) types.AttributeValue { return &types.AttributeValueMemberS{Value: v} }
+func n(v int) types.AttributeValue    { return &types.AttributeValueMemberN{Value: strconv.Itoa(v)} }
+
+func put(t *testing.T, ctx context.Context, db *dynamodb.Client, items ...map[string]types.AttributeValue) {
+	for _, i := range items {
+		_, err := db.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("Table"), Item: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTables(t *testing.T) {
+	ctx, db, cleanup := setup(t)
+	defer cleanup()
+
+	out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("Table")})
+	assert.NoError(t, err)
+	assert.Equal(t, types.TableStatusActive, out.Table.TableStatus)
+	assert.Equal(t, types.BillingModePayPerRequest, out.Table.BillingModeSummary.BillingMode)
+	assert.Equal(t, types.IndexStatusActive, out.Table.GlobalSecondaryIndexes[0].IndexStatus)
+	assert.NotNil(t, out.Table.CreationDateTime)
+
+	_, err = db.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:            aws.String("Table"),
+		BillingMode:          types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS}},
+		KeySchema:            []types.KeySchemaElement{{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash}},
+	})
+	var inUse *types.ResourceInUseException
+	assert.True(t, errors.As(err, &inUse))
+
+	_, err = db.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String("Table")})
+	assert.NoError(t, err)
+	_, err = db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("Table")})
+	var notFound *types.ResourceNotFoundException
+	assert.True(t, errors.As(err, &notFound))
+}
+
+func TestItems(t *testing.T) {
+	ctx, db, cleanup := setup(t)
+	defer cleanup()
+	key := map[string]types.AttributeValue{"pk": s("1"), "sk": s("a")}
+	put(t, ctx, db, map[string]types.AttributeValue{"pk": s("1"), "sk": s("a"), "count": n(1)})
+
+	t.Run("condition", func(t *testing.T) {
+		_, err := db.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String("Table"),
+			Item:                key,
+			ConditionExpression: aws.String("attribute_not_exists(pk)"),
+		})
+		var failed *types.ConditionalCheckFailedException
+		assert.True(t, errors.As(err, &failed))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		out, err := db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:        aws.String("Table"),
+			Key:              key,
+			UpdateExpression: aws.String("SET #count = #count + :one, tags = list_append(if_not_exists(tags, :empty), :tags) ADD colors :colors"),
+			ExpressionAttributeNames: map[string]string{
+				"#count": "count",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":one":    n(1),
+				":empty":  &types.AttributeValueMemberL{},
+				":tags":   &types.AttributeValueMemberL{Value: []types.AttributeValue{s("new")}},
+				":colors": &types.AttributeValueMemberSS{Value: []string{"red"}},
+			},
+			ReturnValues: types.ReturnValueAllNew,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]types.AttributeValue{
+			"pk":     s("1"),
+			"sk":     s("a"),
+			"count":  n(2),
+			"tags":   &types.AttributeValueMemberL{Value: []types.AttributeValue{s("new")}},
+			"colors": &types.AttributeValueMemberSS{Value: []string{"red"}},
+		}, out.Attributes)
+
+		_, err = db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:        aws.String("Table"),
+			Key:              key,
+			UpdateExpression: aws.String("REMOVE tags[0] DELETE colors :colors"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":colors": &types.AttributeValueMemberSS{Value: []string{"red"}},
+			},
+		})
+		assert.NoError(t, err)
+		got, err := db.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName:            aws.String("Table"),
+			Key:                  key,
+			ProjectionExpression: aws.String("tags, colors, #count"),
+			ExpressionAttributeNames: map[string]string{
+				"#count": "count",
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]types.AttributeValue{
+			"count": n(2),
+			"tags":  &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+		}, got.Item)
+	})
+
+	t.Run("unused value", func(t *testing.T) {
+		_, err := db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName:                 aws.String("Table"),
+			Key:                       key,
+			ExpressionAttributeValues: map[string]types.AttributeValue{":unused": n(1)},
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unused in expressions: keys: {:unused}")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		out, err := db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName:    aws.String("Table"),
+			Key:          key,
+			ReturnValues: types.ReturnValueAllOld,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, n(2), out.Attributes["count"])
+
+		got, err := db.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String("Table"), Key: key})
+		assert.NoError(t, err)
+		assert.Nil(t, got.Item)
+	})
+}
+
+func TestQuery(t *testing.T) {
+	ctx, db, cleanup := setup(t)
+	defer cleanup()
+	for i := 0; i < 5; i++ {
+		put(t, ctx, db, map[string]types.AttributeValue{
+			"pk": s("1"), "sk": s("item#" + strconv.Itoa(i)), "lsi_sk": n(10 - i), "gsi_pk": s("group"), "data": n(i),
+		})
+	}
+	put(t, ctx, db, map[string]types.AttributeValue{"pk": s("2"), "sk": s("item#0")})
+
+	t.Run("range condition", func(t *testing.T) {
+		out, err := db.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String("Table"),
+			KeyConditionExpression: aws.String("pk = :pk AND sk BETWEEN :from AND :to"),
+			FilterExpression:       aws.String("#data <> :skip"),
+			ExpressionAttributeNames: map[string]string{
+				"#data": "data",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": s("1"), ":from": s("item#1"), ":to": s("item#3"), ":skip": n(2),
+			},
+			ScanIndexForward: aws.Bool(false),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), out.Count)
+		assert.Equal(t, int32(3), out.ScannedCount)
+		assert.Equal(t, s("item#3"), out.Items[0]["sk"])
+		assert.Equal(t, s("item#1"), out.Items[1]["sk"])
+	})
+
+	t.Run("local index pages", func(t *testing.T) {
+		var sks []types.AttributeValue
+		var start map[string]types.AttributeValue
+		pages := 0
+		for {
+			out, err := db.Query(ctx, &dynamodb.QueryInput{
+				TableName:                 aws.String("Table"),
+				IndexName:                 aws.String("ByNumber"),
+				KeyConditionExpression:    aws.String("pk = :pk AND lsi_sk > :min"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{":pk": s("1"), ":min": n(6)},
+				Limit:                     aws.Int32(2),
+				ExclusiveStartKey:         start,
+			})
+			assert.NoError(t, err)
+			pages++
+			for _, i := range out.Items {
+				sks = append(sks, i["sk"])
+			}
+			if out.LastEvaluatedKey == nil {
+				break
+			}
+			start = out.LastEvaluatedKey
+		}
+		assert.Equal(t, 2, pages)
+		assert.Equal(t, []types.AttributeValue{s("item#3"), s("item#2"), s("item#1"), s("item#0")}, sks)
+	})
+
+	t.Run("global index projection", func(t *testing.T) {
+		out, err := db.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String("Table"),
+			IndexName:                 aws.String("ByGroup"),
+			KeyConditionExpression:    aws.String("gsi_pk = :group"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":group": s("group")},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, out.Items, 5)
+		assert.Equal(t, map[string]types.AttributeValue{"pk": s("1"), "sk": s("item#0"), "gsi_pk": s("group")}, out.Items[0])
+	})
+
+	t.Run("missing hash key", func(t *testing.T) {
+		_, err := db.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String("Table"),
+			KeyConditionExpression:    aws.String("sk = :sk"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":sk": s("item#0")},
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "Query condition missed key schema element: pk")
+	})
+}
+
+func TestScan(t *testing.T) {
+	ctx, db, cleanup := setup(t)
+	defer cleanup()
+	for i := 0; i < 20; i++ {
+		put(t, ctx, db, map[string]types.AttributeValue{"pk": s(strconv.Itoa(i)), "sk": s("item")})
+	}
+
+	seen := make(map[string]bool)
+	for segment := int32(0); segment < 3; segment++ {
+		out, err := db.Scan(ctx, &dynamodb.ScanInput{
+			TableName:     aws.String("Table"),
+			Segment:       aws.Int32(segment),
+			TotalSegments: aws.Int32(3),
+		})
+		assert.NoError(t, err)
+		for _, i := range out.Items {
+			pk := i["pk"].(*types.AttributeValueMemberS).Value
+			assert.False(t, seen[pk])
+			seen[pk] = true
+		}
+	}
+	assert.Len(t, seen, 20)
+}
+
+func TestTransactWriteItems(t *testing.T) {
+	ctx, db, cleanup := setup(t)
+	defer cleanup()
+	put(t, ctx, db, map[string]types.AttributeValue{"pk": s("1"), "sk": s("a"), "state": s("taken")})
+
+	_, err := db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName: aws.String("Table"),
+				Item:      map[string]types.AttributeValue{"pk": s("2"), "sk": s("a")},
+			}},
+			{Put: &types.Put{
+				TableName:                           aws.String("Table"),
+				Item:                                map[string]types.AttributeValue{"pk": s("1"), "sk": s("a")},
+				ConditionExpression:                 aws.String("attribute_not_exists(pk)"),
+				ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+			}},
+		},
+	})
+	var cancelled *types.TransactionCanceledException
+	if assert.True(t, errors.As(err, &cancelled)) {
+		assert.Equal(t, "None", *cancelled.CancellationReasons[0].Code)
+		assert.Equal(t, "ConditionalCheckFailed", *cancelled.CancellationReasons[1].Code)
+		assert.Equal(t, s("taken"), cancelled.CancellationReasons[1].Item["state"])
+	}
+
+	got, err := db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("Table"),
+		Key:       map[string]types.AttributeValue{"pk": s("2"), "sk": s("a")},
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, got.Item)
+}
+
+func TestBatchWriteItem(t *testing.T) {
+	ctx := context.Background()
+	mem := memdb.New()
+	srv := httptest.NewServer(mem)
+	defer srv.Close()
+	db, err := dynamo.NewClient(ctx, dynamo.WithEndpoint(srv.URL))
+	assert.NoError(t, err)
+	_, err = db.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:            aws.String("Table"),
+		BillingMode:          types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS}},
+		KeySchema:            []types.KeySchemaElement{{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash}},
+	})
+	assert.NoError(t, err)
+	put(t, ctx, db, map[string]types.AttributeValue{"pk": s("old")})
+
+	mem.LimitBatch(2)
+	out, err := db.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]types.WriteRequest{"Table": {
+			{DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{"pk": s("old")}}},
+			{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{"pk": s("1")}}},
+			{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{"pk": s("2")}}},
+		}},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, out.UnprocessedItems["Table"], 1) {
+		assert.Equal(t, s("2"), out.UnprocessedItems["Table"][0].PutRequest.Item["pk"])
+	}
+
+	scan, err := db.Scan(ctx, &dynamodb.ScanInput{TableName: aws.String("Table")})
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]types.AttributeValue{{"pk": s("1")}}, scan.Items)
+
+	_, err = db.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]types.WriteRequest{"Table": {
+			{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{"pk": s("1")}}},
+			{DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{"pk": s("1")}}},
+		}},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Provided list of item keys contains duplicates")
+	}
+}
+
+func TestBatchGetItem(t *testing.T) {
+	ctx := context.Background()
+	mem := memdb.New()
+	srv := httptest.NewServer(mem)
+	defer srv.Close()
+	db, err := dynamo.NewClient(ctx, dynamo.WithEndpoint(srv.URL))
+	assert.NoError(t, err)
+	_, err = db.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:            aws.String("Table"),
+		BillingMode:          types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS}},
+		KeySchema:            []types.KeySchemaElement{{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash}},
+	})
+	assert.NoError(t, err)
+	put(t, ctx, db, map[string]types.AttributeValue{"pk": s("1"), "a": s("a1"), "b": s("b1")})
+	put(t, ctx, db, map[string]types.AttributeValue{"pk": s("2"), "a": s("a2"), "b": s("b2")})
+
+	mem.LimitBatch(2)
+	out, err := db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]types.KeysAndAttributes{"Table": {
+			Keys: []map[string]types.AttributeValue{
+				{"pk": s("1")},
+				{"pk": s("missing")},
+				{"pk": s("2")},
+			},
+			ProjectionExpression:     aws.String("#pk, a"),
+			ExpressionAttributeNames: map[string]string{"#pk": "pk"},
+			ConsistentRead:           aws.Bool(true),
+		}},
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]types.AttributeValue{{"pk": s("1"), "a": s("a1")}}, out.Responses["Table"])
+	unprocessed := out.UnprocessedKeys["Table"]
+	assert.Equal(t, []map[string]types.AttributeValue{{"pk": s("2")}}, unprocessed.Keys)
+	assert.Equal(t, "#pk, a", aws.ToString(unprocessed.ProjectionExpression))
+	assert.True(t, aws.ToBool(unprocessed.ConsistentRead))
+	if assert.Len(t, out.ConsumedCapacity, 1) {
+		assert.Equal(t, 2.0, aws.ToFloat64(out.ConsumedCapacity[0].CapacityUnits))
+	}
+
+	_, err = db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]types.KeysAndAttributes{"Table": {
+			Keys: []map[string]types.AttributeValue{{"pk": s("1")}, {"pk": s("1")}},
+		}},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Provided list of item keys contains duplicates")
+	}
+}
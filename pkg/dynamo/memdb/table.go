@@ -0,0 +1,857 @@
+package memdb
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const arnPrefix = "arn:aws:dynamodb:local:000000000000:table/"
+
+type table struct {
+	desc    types.TableDescription
+	created time.Time
+	ttl     types.TimeToLiveDescription
+	pitr    bool
+	tags    []types.Tag
+	items   map[string]item
+}
+
+// index describes the way items are organised: either by the table's primary key or by one of its indexes.
+type index struct {
+	name       string
+	hash, rng  string
+	projection *types.Projection
+	global     bool
+}
+
+func keyAttributes(schema []types.KeySchemaElement) (hash, rng string) {
+	for _, k := range schema {
+		if k.KeyType == types.KeyTypeHash {
+			hash = aws.ToString(k.AttributeName)
+		} else {
+			rng = aws.ToString(k.AttributeName)
+		}
+	}
+	return hash, rng
+}
+
+func (t *table) primary() index {
+	hash, rng := keyAttributes(t.desc.KeySchema)
+	return index{hash: hash, rng: rng}
+}
+
+func (t *table) index(name string) (index, error) {
+	if name == "" {
+		return t.primary(), nil
+	}
+	for _, lsi := range t.desc.LocalSecondaryIndexes {
+		if aws.ToString(lsi.IndexName) == name {
+			hash, rng := keyAttributes(lsi.KeySchema)
+			return index{name: name, hash: hash, rng: rng, projection: lsi.Projection}, nil
+		}
+	}
+	for _, gsi := range t.desc.GlobalSecondaryIndexes {
+		if aws.ToString(gsi.IndexName) == name {
+			hash, rng := keyAttributes(gsi.KeySchema)
+			if gsi.IndexStatus != types.IndexStatusActive {
+				return index{}, validationError("Cannot read from backfilling global secondary index: %s", name)
+			}
+			return index{name: name, hash: hash, rng: rng, projection: gsi.Projection, global: true}, nil
+		}
+	}
+	return index{}, validationError("The table does not have the specified index: %s", name)
+}
+
+func (t *table) attributeType(name string) types.ScalarAttributeType {
+	for _, def := range t.desc.AttributeDefinitions {
+		if aws.ToString(def.AttributeName) == name {
+			return def.AttributeType
+		}
+	}
+	return ""
+}
+
+// key extracts the primary key from the item.
+func (t *table) key(i item) item {
+	pk := t.primary()
+	key := item{pk.hash: i[pk.hash]}
+	if pk.rng != "" {
+		key[pk.rng] = i[pk.rng]
+	}
+	return key
+}
+
+func (t *table) id(i item) string {
+	pk := t.primary()
+	return keyOf(i, pk.hash, pk.rng)
+}
+
+// checkKey validates that `key` is exactly the primary key of the table.
+func (t *table) checkKey(key item) error {
+	pk := t.primary()
+	want := 1
+	if pk.rng != "" {
+		want = 2
+	}
+	if len(key) != want {
+		return validationError("The provided key element does not match the schema")
+	}
+	for _, name := range []string{pk.hash, pk.rng} {
+		if name == "" {
+			continue
+		}
+		if err := t.checkKeyAttribute(key, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *table) checkKeyAttribute(i item, name string) error {
+	av, ok := i[name]
+	if !ok || typeOf(av) != string(t.attributeType(name)) {
+		return validationError("The provided key element does not match the schema")
+	}
+	if size(av) == 0 {
+		return validationError("One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: %s", name)
+	}
+	return nil
+}
+
+// checkItem validates the item that is about to be stored in the table.
+func (t *table) checkItem(i item) error {
+	if err := t.checkKey(t.key(i)); err != nil {
+		return validationError("One or more parameter values were invalid: Missing the key %s in the item", strings.Join(t.missingKeys(i), ", "))
+	}
+	for _, def := range t.desc.AttributeDefinitions {
+		name := aws.ToString(def.AttributeName)
+		if av, ok := i[name]; ok && typeOf(av) != string(def.AttributeType) {
+			return validationError("One or more parameter values were invalid: Type mismatch for Index Key %s Expected: %s Actual: %s", name, def.AttributeType, typeOf(av))
+		}
+	}
+	for name, av := range i {
+		if err := checkValue(name, av); err != nil {
+			return err
+		}
+	}
+	if itemSize(i) > 400*1024 {
+		return validationError("Item size has exceeded the maximum allowed size")
+	}
+	return nil
+}
+
+func (t *table) missingKeys(i item) []string {
+	var missing []string
+	pk := t.primary()
+	for _, name := range []string{pk.hash, pk.rng} {
+		if name == "" {
+			continue
+		}
+		if err := t.checkKeyAttribute(i, name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+func checkValue(name string, av types.AttributeValue) error {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberN:
+		if _, ok := parseNumber(v.Value); !ok {
+			return validationError("A value provided cannot be converted into a number")
+		}
+	case *types.AttributeValueMemberL:
+		for _, e := range v.Value {
+			if err := checkValue(name, e); err != nil {
+				return err
+			}
+		}
+	case *types.AttributeValueMemberM:
+		for n, e := range v.Value {
+			if err := checkValue(n, e); err != nil {
+				return err
+			}
+		}
+	default:
+		if isSet(av) {
+			elems := setElements(av)
+			if len(elems) == 0 {
+				return validationError("One or more parameter values were invalid: An number set  may not be empty")
+			}
+			for x := range elems {
+				if err := checkValue(name, elems[x]); err != nil {
+					return err
+				}
+				for y := x + 1; y < len(elems); y++ {
+					if equal(elems[x], elems[y]) {
+						return validationError("One or more parameter values were invalid: Input collection contains duplicates")
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rows returns items that belong to the index. Secondary indexes are sparse, so items without
+// index key attributes are skipped.
+func (t *table) rows(ix index) []item {
+	rows := make([]item, 0, len(t.items))
+	for _, i := range t.items {
+		if _, ok := i[ix.hash]; !ok {
+			continue
+		}
+		if _, ok := i[ix.rng]; ix.rng != "" && !ok {
+			continue
+		}
+		rows = append(rows, i)
+	}
+	return rows
+}
+
+// order compares two items the way they are laid out in the index. Scan orders partitions by
+// the hash of their key, Query only deals with one partition.
+func (t *table) order(ix index) func(a, b item) int {
+	return func(a, b item) int {
+		if ha, hb := partitionHash(a, ix.hash), partitionHash(b, ix.hash); ha != hb {
+			if ha < hb {
+				return -1
+			}
+			return 1
+		}
+		if c := strings.Compare(keyOf(a, ix.hash), keyOf(b, ix.hash)); c != 0 {
+			return c
+		}
+		if ix.rng != "" {
+			if c, _ := compare(a[ix.rng], b[ix.rng]); c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(t.id(a), t.id(b))
+	}
+}
+
+func partitionHash(i item, hash string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(keyOf(i, hash)))
+	return h.Sum32()
+}
+
+// indexKey returns attributes identifying the position of the item in the index, returned as LastEvaluatedKey.
+func (t *table) indexKey(ix index, i item) item {
+	key := t.key(i)
+	key[ix.hash] = i[ix.hash]
+	if ix.rng != "" {
+		key[ix.rng] = i[ix.rng]
+	}
+	return copyItem(key)
+}
+
+// projectIndex returns attributes of the item projected into the index.
+func (t *table) projectIndex(ix index, i item) item {
+	if ix.projection == nil || ix.projection.ProjectionType == types.ProjectionTypeAll {
+		return copyItem(i)
+	}
+	out := t.indexKey(ix, i)
+	if ix.projection.ProjectionType == types.ProjectionTypeInclude {
+		for _, name := range ix.projection.NonKeyAttributes {
+			if av, ok := i[name]; ok {
+				out[name] = copyValue(av)
+			}
+		}
+	}
+	return out
+}
+
+func (t *table) describe() map[string]interface{} {
+	t.desc.ItemCount = int64(len(t.items))
+	t.desc.TableSizeBytes = 0
+	for _, i := range t.items {
+		t.desc.TableSizeBytes += int64(itemSize(i))
+	}
+	for n := range t.desc.GlobalSecondaryIndexes {
+		gsi := &t.desc.GlobalSecondaryIndexes[n]
+		hash, rng := keyAttributes(gsi.KeySchema)
+		rows := t.rows(index{hash: hash, rng: rng})
+		gsi.ItemCount = int64(len(rows))
+		gsi.IndexSizeBytes = 0
+		for _, i := range rows {
+			gsi.IndexSizeBytes += int64(itemSize(i))
+		}
+	}
+	desc := compact(t.desc).(map[string]interface{})
+	desc["CreationDateTime"] = compact(t.created)
+	return desc
+}
+
+// backfill completes creation of global indexes. It's called once the index was described
+// as being created, so that clients see the index going through CREATING to ACTIVE.
+func (t *table) backfill() {
+	for n := range t.desc.GlobalSecondaryIndexes {
+		if gsi := &t.desc.GlobalSecondaryIndexes[n]; gsi.IndexStatus == types.IndexStatusCreating {
+			gsi.IndexStatus = types.IndexStatusActive
+			gsi.Backfilling = nil
+		}
+	}
+}
+
+func (db *DB) table(name *string) (*table, error) {
+	t, ok := db.tables[aws.ToString(name)]
+	if !ok {
+		return nil, resourceNotFound()
+	}
+	return t, nil
+}
+
+func (db *DB) tableByARN(arn *string) (*table, error) {
+	return db.table(aws.String(strings.TrimPrefix(aws.ToString(arn), arnPrefix)))
+}
+
+func (db *DB) createTable(body []byte) (interface{}, error) {
+	var in dynamodb.CreateTableInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	t, err := newTable(in)
+	if err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.tables[aws.ToString(in.TableName)]; ok {
+		return nil, &apiError{code: "ResourceInUseException", message: "Cannot create preexisting table"}
+	}
+	db.tables[aws.ToString(in.TableName)] = t
+	return map[string]interface{}{"TableDescription": t.describe()}, nil
+}
+
+func newTable(in dynamodb.CreateTableInput) (*table, error) {
+	name := aws.ToString(in.TableName)
+	if len(name) < 3 || len(name) > 255 {
+		return nil, validationError("TableName must be at least 3 characters long and at most 255 characters long")
+	}
+	billing := in.BillingMode
+	if billing == "" {
+		billing = types.BillingModeProvisioned
+	}
+	if err := checkThroughput(billing, in.ProvisionedThroughput); err != nil {
+		return nil, err
+	}
+	defined := make(map[string]bool)
+	for _, def := range in.AttributeDefinitions {
+		defined[aws.ToString(def.AttributeName)] = true
+	}
+	used := make(map[string]bool)
+	checkSchema := func(schema []types.KeySchemaElement) error {
+		hash, rng := keyAttributes(schema)
+		if hash == "" || len(schema) > 2 || (len(schema) == 2 && rng == "") {
+			return validationError("Invalid KeySchema: Some index key attribute have no definition")
+		}
+		for _, k := range schema {
+			if !defined[aws.ToString(k.AttributeName)] {
+				return validationError("One or more parameter values were invalid: Some index key attributes are not defined in AttributeDefinitions. Keys: [%s], AttributeDefinitions: %v", aws.ToString(k.AttributeName), definitionNames(in.AttributeDefinitions))
+			}
+			used[aws.ToString(k.AttributeName)] = true
+		}
+		return nil
+	}
+	if err := checkSchema(in.KeySchema); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	arn := arnPrefix + name
+	t := &table{
+		created: now,
+		items:   make(map[string]item),
+		tags:    in.Tags,
+		desc: types.TableDescription{
+			TableName:             in.TableName,
+			TableArn:              aws.String(arn),
+			TableId:               aws.String(fmt.Sprintf("%x", now.UnixNano())),
+			TableStatus:           types.TableStatusActive,
+			AttributeDefinitions:  in.AttributeDefinitions,
+			KeySchema:             in.KeySchema,
+			BillingModeSummary:    &types.BillingModeSummary{BillingMode: billing},
+			ProvisionedThroughput: throughput(in.ProvisionedThroughput),
+		},
+	}
+	names := make(map[string]bool)
+	if len(in.LocalSecondaryIndexes) > 5 {
+		return nil, validationError("One or more parameter values were invalid: Number of LocalSecondaryIndexes exceeds per-table limit of 5")
+	}
+	pk := t.primary()
+	for _, lsi := range in.LocalSecondaryIndexes {
+		if err := checkSchema(lsi.KeySchema); err != nil {
+			return nil, err
+		}
+		hash, rng := keyAttributes(lsi.KeySchema)
+		if hash != pk.hash || rng == "" || pk.rng == "" {
+			return nil, validationError("One or more parameter values were invalid: Table KeySchema does not have a range key, which is required when specifying a LocalSecondaryIndex")
+		}
+		if err := checkIndex(names, lsi.IndexName, lsi.Projection); err != nil {
+			return nil, err
+		}
+		t.desc.LocalSecondaryIndexes = append(t.desc.LocalSecondaryIndexes, types.LocalSecondaryIndexDescription{
+			IndexName:  lsi.IndexName,
+			IndexArn:   aws.String(arn + "/index/" + aws.ToString(lsi.IndexName)),
+			KeySchema:  lsi.KeySchema,
+			Projection: lsi.Projection,
+		})
+	}
+	if len(in.GlobalSecondaryIndexes) > 20 {
+		return nil, validationError("One or more parameter values were invalid: Number of GlobalSecondaryIndexes exceeds per-table limit of 20")
+	}
+	for _, gsi := range in.GlobalSecondaryIndexes {
+		if err := checkSchema(gsi.KeySchema); err != nil {
+			return nil, err
+		}
+		if err := checkIndex(names, gsi.IndexName, gsi.Projection); err != nil {
+			return nil, err
+		}
+		if err := checkThroughput(billing, gsi.ProvisionedThroughput); err != nil {
+			return nil, err
+		}
+		t.desc.GlobalSecondaryIndexes = append(t.desc.GlobalSecondaryIndexes, globalIndex(arn, gsi.IndexName, gsi.KeySchema, gsi.Projection, gsi.ProvisionedThroughput))
+	}
+	for name := range defined {
+		if !used[name] {
+			return nil, validationError("One or more parameter values were invalid: Number of attributes in KeySchema does not exactly match number of attributes defined in AttributeDefinitions")
+		}
+	}
+	if s := in.StreamSpecification; s != nil && s.StreamEnabled != nil && *s.StreamEnabled {
+		label := now.UTC().Format("2006-01-02T15:04:05.000")
+		t.desc.StreamSpecification = s
+		t.desc.LatestStreamLabel = aws.String(label)
+		t.desc.LatestStreamArn = aws.String(arn + "/stream/" + label)
+	}
+	if s := in.SSESpecification; s != nil && s.Enabled != nil && *s.Enabled {
+		t.desc.SSEDescription = &types.SSEDescription{
+			Status:  types.SSEStatusEnabled,
+			SSEType: types.SSETypeKms,
+		}
+	}
+	t.ttl = types.TimeToLiveDescription{TimeToLiveStatus: types.TimeToLiveStatusDisabled}
+	return t, nil
+}
+
+func throughput(p *types.ProvisionedThroughput) *types.ProvisionedThroughputDescription {
+	desc := &types.ProvisionedThroughputDescription{
+		NumberOfDecreasesToday: aws.Int64(0),
+		ReadCapacityUnits:      aws.Int64(0),
+		WriteCapacityUnits:     aws.Int64(0),
+	}
+	if p != nil {
+		desc.ReadCapacityUnits = p.ReadCapacityUnits
+		desc.WriteCapacityUnits = p.WriteCapacityUnits
+	}
+	return desc
+}
+
+func globalIndex(arn string, name *string, schema []types.KeySchemaElement, p *types.Projection, tp *types.ProvisionedThroughput) types.GlobalSecondaryIndexDescription {
+	return types.GlobalSecondaryIndexDescription{
+		IndexName:             name,
+		IndexArn:              aws.String(arn + "/index/" + aws.ToString(name)),
+		IndexStatus:           types.IndexStatusActive,
+		KeySchema:             schema,
+		Projection:            p,
+		ProvisionedThroughput: throughput(tp),
+	}
+}
+
+func definitionNames(defs []types.AttributeDefinition) []string {
+	names := make([]string, 0, len(defs))
+	for _, def := range defs {
+		names = append(names, aws.ToString(def.AttributeName))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func checkIndex(names map[string]bool, name *string, p *types.Projection) error {
+	if aws.ToString(name) == "" {
+		return validationError("One or more parameter values were invalid: IndexName must be specified")
+	}
+	if names[aws.ToString(name)] {
+		return validationError("One or more parameter values were invalid: Duplicate index name: %s", aws.ToString(name))
+	}
+	names[aws.ToString(name)] = true
+	if p == nil {
+		return validationError("One or more parameter values were invalid: Projection must be specified for index %s", aws.ToString(name))
+	}
+	return nil
+}
+
+func checkThroughput(billing types.BillingMode, p *types.ProvisionedThroughput) error {
+	switch billing {
+	case types.BillingModeProvisioned:
+		if p == nil || aws.ToInt64(p.ReadCapacityUnits) < 1 || aws.ToInt64(p.WriteCapacityUnits) < 1 {
+			return validationError("One or more parameter values were invalid: ReadCapacityUnits and WriteCapacityUnits must both be specified when BillingMode is PROVISIONED")
+		}
+	case types.BillingModePayPerRequest:
+		if p != nil {
+			return validationError("One or more parameter values were invalid: Neither ReadCapacityUnits nor WriteCapacityUnits can be specified when BillingMode is PAY_PER_REQUEST")
+		}
+	default:
+		return validationError("1 validation error detected: Value '%s' at 'billingMode' failed to satisfy constraint", billing)
+	}
+	return nil
+}
+
+func (db *DB) describeTable(body []byte) (interface{}, error) {
+	var in dynamodb.DescribeTableInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	desc := t.describe()
+	t.backfill()
+	return map[string]interface{}{"Table": desc}, nil
+}
+
+// updateTable changes billing mode, throughput, stream or global secondary indexes of the table.
+// Like DynamoDB, it creates or deletes only one index at a time.
+func (db *DB) updateTable(body []byte) (interface{}, error) {
+	var in dynamodb.UpdateTableInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	for _, gsi := range t.desc.GlobalSecondaryIndexes {
+		if gsi.IndexStatus != types.IndexStatusActive {
+			return nil, &apiError{code: "ResourceInUseException", message: "Attempt to change a resource which is still in use: Table is being updated"}
+		}
+	}
+	// Changes are made on the copy, so that invalid update leaves the table untouched.
+	desc := t.desc
+	desc.AttributeDefinitions = append([]types.AttributeDefinition{}, t.desc.AttributeDefinitions...)
+	desc.GlobalSecondaryIndexes = append([]types.GlobalSecondaryIndexDescription{}, t.desc.GlobalSecondaryIndexes...)
+	for _, def := range in.AttributeDefinitions {
+		switch typ := (&table{desc: desc}).attributeType(aws.ToString(def.AttributeName)); typ {
+		case "":
+			desc.AttributeDefinitions = append(desc.AttributeDefinitions, def)
+		case def.AttributeType:
+		default:
+			return nil, validationError("Cannot change type of attribute %s from %s to %s", aws.ToString(def.AttributeName), typ, def.AttributeType)
+		}
+	}
+
+	billing := desc.BillingModeSummary.BillingMode
+	if in.BillingMode != "" && in.BillingMode != billing {
+		billing = in.BillingMode
+		desc.BillingModeSummary = &types.BillingModeSummary{BillingMode: billing}
+		if billing == types.BillingModePayPerRequest {
+			desc.ProvisionedThroughput = throughput(nil)
+			for n := range desc.GlobalSecondaryIndexes {
+				desc.GlobalSecondaryIndexes[n].ProvisionedThroughput = throughput(nil)
+			}
+		} else if in.ProvisionedThroughput == nil {
+			return nil, validationError("One or more parameter values were invalid: ProvisionedThroughput must be specified when BillingMode is PROVISIONED")
+		}
+	}
+	if in.ProvisionedThroughput != nil {
+		if err := checkThroughput(billing, in.ProvisionedThroughput); err != nil {
+			return nil, err
+		}
+		desc.ProvisionedThroughput = throughput(in.ProvisionedThroughput)
+	}
+
+	changes := 0
+	for _, u := range in.GlobalSecondaryIndexUpdates {
+		switch {
+		case u.Create != nil:
+			changes++
+			c := u.Create
+			if err := checkThroughput(billing, c.ProvisionedThroughput); err != nil {
+				return nil, err
+			}
+			names := make(map[string]bool)
+			for _, lsi := range desc.LocalSecondaryIndexes {
+				names[aws.ToString(lsi.IndexName)] = true
+			}
+			for _, gsi := range desc.GlobalSecondaryIndexes {
+				names[aws.ToString(gsi.IndexName)] = true
+			}
+			if err := checkIndex(names, c.IndexName, c.Projection); err != nil {
+				return nil, err
+			}
+			hash, rng := keyAttributes(c.KeySchema)
+			for _, name := range []string{hash, rng} {
+				if name != "" && (&table{desc: desc}).attributeType(name) == "" {
+					return nil, validationError("One or more parameter values were invalid: Some index key attributes are not defined in AttributeDefinitions. Keys: [%s]", name)
+				}
+			}
+			gsi := globalIndex(aws.ToString(desc.TableArn), c.IndexName, c.KeySchema, c.Projection, c.ProvisionedThroughput)
+			gsi.IndexStatus = types.IndexStatusCreating
+			gsi.Backfilling = aws.Bool(true)
+			desc.GlobalSecondaryIndexes = append(desc.GlobalSecondaryIndexes, gsi)
+		case u.Delete != nil:
+			changes++
+			n := findGlobalIndex(desc, u.Delete.IndexName)
+			if n < 0 {
+				return nil, resourceNotFound()
+			}
+			desc.GlobalSecondaryIndexes = append(desc.GlobalSecondaryIndexes[:n], desc.GlobalSecondaryIndexes[n+1:]...)
+		case u.Update != nil:
+			n := findGlobalIndex(desc, u.Update.IndexName)
+			if n < 0 {
+				return nil, resourceNotFound()
+			}
+			if err := checkThroughput(billing, u.Update.ProvisionedThroughput); err != nil {
+				return nil, err
+			}
+			desc.GlobalSecondaryIndexes[n].ProvisionedThroughput = throughput(u.Update.ProvisionedThroughput)
+		}
+	}
+	if changes > 1 {
+		return nil, validationError("Subscriber limit exceeded: Only 1 online index can be created or deleted simultaneously per table")
+	}
+	if len(desc.GlobalSecondaryIndexes) > 20 {
+		return nil, validationError("One or more parameter values were invalid: Number of GlobalSecondaryIndexes exceeds per-table limit of 20")
+	}
+	if billing == types.BillingModeProvisioned {
+		for _, gsi := range desc.GlobalSecondaryIndexes {
+			if aws.ToInt64(gsi.ProvisionedThroughput.ReadCapacityUnits) < 1 {
+				return nil, validationError("One or more parameter values were invalid: ProvisionedThroughput must be specified for index: %s", aws.ToString(gsi.IndexName))
+			}
+		}
+	}
+
+	if s := in.StreamSpecification; s != nil {
+		enabled := desc.StreamSpecification != nil && aws.ToBool(desc.StreamSpecification.StreamEnabled)
+		switch {
+		case aws.ToBool(s.StreamEnabled) && enabled:
+			return nil, validationError("Table already has an enabled stream: %s", aws.ToString(desc.LatestStreamArn))
+		case aws.ToBool(s.StreamEnabled):
+			label := time.Now().UTC().Format("2006-01-02T15:04:05.000")
+			desc.StreamSpecification = s
+			desc.LatestStreamLabel = aws.String(label)
+			desc.LatestStreamArn = aws.String(aws.ToString(desc.TableArn) + "/stream/" + label)
+		case !enabled:
+			return nil, validationError("Table already has no stream enabled")
+		default:
+			desc.StreamSpecification = nil
+		}
+	}
+	desc.AttributeDefinitions = usedDefinitions(desc)
+	t.desc = desc
+	out := t.describe()
+	out["TableStatus"] = types.TableStatusUpdating
+	return map[string]interface{}{"TableDescription": out}, nil
+}
+
+func findGlobalIndex(desc types.TableDescription, name *string) int {
+	for n, gsi := range desc.GlobalSecondaryIndexes {
+		if aws.ToString(gsi.IndexName) == aws.ToString(name) {
+			return n
+		}
+	}
+	return -1
+}
+
+// usedDefinitions drops definitions of attributes, that are no longer part of any key.
+func usedDefinitions(desc types.TableDescription) []types.AttributeDefinition {
+	used := make(map[string]bool)
+	schemas := [][]types.KeySchemaElement{desc.KeySchema}
+	for _, lsi := range desc.LocalSecondaryIndexes {
+		schemas = append(schemas, lsi.KeySchema)
+	}
+	for _, gsi := range desc.GlobalSecondaryIndexes {
+		schemas = append(schemas, gsi.KeySchema)
+	}
+	for _, schema := range schemas {
+		for _, k := range schema {
+			used[aws.ToString(k.AttributeName)] = true
+		}
+	}
+	var defs []types.AttributeDefinition
+	for _, def := range desc.AttributeDefinitions {
+		if used[aws.ToString(def.AttributeName)] {
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// deleteTable drops the table right away, there is no DELETING phase to wait for.
+func (db *DB) deleteTable(body []byte) (interface{}, error) {
+	var in dynamodb.DeleteTableInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	delete(db.tables, aws.ToString(in.TableName))
+	desc := t.describe()
+	desc["TableStatus"] = types.TableStatusDeleting
+	return map[string]interface{}{"TableDescription": desc}, nil
+}
+
+func (db *DB) listTables(body []byte) (interface{}, error) {
+	var in dynamodb.ListTablesInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	names := make([]string, 0, len(db.tables))
+	for name := range db.tables {
+		if name > aws.ToString(in.ExclusiveStartTableName) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	out := map[string]interface{}{}
+	limit := int(aws.ToInt32(in.Limit))
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	if len(names) > limit {
+		names = names[:limit]
+		out["LastEvaluatedTableName"] = names[limit-1]
+	}
+	out["TableNames"] = names
+	return out, nil
+}
+
+func (db *DB) updateTimeToLive(body []byte) (interface{}, error) {
+	var in dynamodb.UpdateTimeToLiveInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if in.TimeToLiveSpecification == nil {
+		return nil, validationError("TimeToLiveSpecification must be specified")
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	spec := in.TimeToLiveSpecification
+	if aws.ToBool(spec.Enabled) {
+		t.ttl = types.TimeToLiveDescription{AttributeName: spec.AttributeName, TimeToLiveStatus: types.TimeToLiveStatusEnabled}
+	} else {
+		t.ttl = types.TimeToLiveDescription{TimeToLiveStatus: types.TimeToLiveStatusDisabled}
+	}
+	return map[string]interface{}{"TimeToLiveSpecification": spec}, nil
+}
+
+func (db *DB) describeTimeToLive(body []byte) (interface{}, error) {
+	var in dynamodb.DescribeTimeToLiveInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"TimeToLiveDescription": t.ttl}, nil
+}
+
+func (db *DB) updateContinuousBackups(body []byte) (interface{}, error) {
+	var in dynamodb.UpdateContinuousBackupsInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if in.PointInTimeRecoverySpecification == nil {
+		return nil, validationError("PointInTimeRecoverySpecification must be specified")
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	t.pitr = aws.ToBool(in.PointInTimeRecoverySpecification.PointInTimeRecoveryEnabled)
+	return map[string]interface{}{"ContinuousBackupsDescription": t.backups()}, nil
+}
+
+func (db *DB) describeContinuousBackups(body []byte) (interface{}, error) {
+	var in dynamodb.DescribeContinuousBackupsInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"ContinuousBackupsDescription": t.backups()}, nil
+}
+
+func (t *table) backups() types.ContinuousBackupsDescription {
+	status := types.PointInTimeRecoveryStatusDisabled
+	if t.pitr {
+		status = types.PointInTimeRecoveryStatusEnabled
+	}
+	return types.ContinuousBackupsDescription{
+		ContinuousBackupsStatus: types.ContinuousBackupsStatusEnabled,
+		PointInTimeRecoveryDescription: &types.PointInTimeRecoveryDescription{
+			PointInTimeRecoveryStatus: status,
+		},
+	}
+}
+
+func (db *DB) tagResource(body []byte) (interface{}, error) {
+	var in dynamodb.TagResourceInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.tableByARN(in.ResourceArn)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range in.Tags {
+		replaced := false
+		for n := range t.tags {
+			if aws.ToString(t.tags[n].Key) == aws.ToString(tag.Key) {
+				t.tags[n].Value = tag.Value
+				replaced = true
+			}
+		}
+		if !replaced {
+			t.tags = append(t.tags, tag)
+		}
+	}
+	return map[string]interface{}{}, nil
+}
+
+func (db *DB) listTagsOfResource(body []byte) (interface{}, error) {
+	var in dynamodb.ListTagsOfResourceInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.tableByARN(in.ResourceArn)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"Tags": t.tags}, nil
+}
@@ -0,0 +1,298 @@
+package memdb
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"strings"
+
+	"dynamodb-with-go/pkg/dynamo/ddbjson"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type item = map[string]types.AttributeValue
+
+// typeOf returns DynamoDB type descriptor of the value.
+func typeOf(av types.AttributeValue) string {
+	switch av.(type) {
+	case *types.AttributeValueMemberS:
+		return "S"
+	case *types.AttributeValueMemberN:
+		return "N"
+	case *types.AttributeValueMemberB:
+		return "B"
+	case *types.AttributeValueMemberBOOL:
+		return "BOOL"
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	case *types.AttributeValueMemberSS:
+		return "SS"
+	case *types.AttributeValueMemberNS:
+		return "NS"
+	case *types.AttributeValueMemberBS:
+		return "BS"
+	case *types.AttributeValueMemberL:
+		return "L"
+	case *types.AttributeValueMemberM:
+		return "M"
+	}
+	return ""
+}
+
+func copyItem(i item) item {
+	if i == nil {
+		return nil
+	}
+	out := make(item, len(i))
+	for name, av := range i {
+		out[name] = copyValue(av)
+	}
+	return out
+}
+
+func copyValue(av types.AttributeValue) types.AttributeValue {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return &types.AttributeValueMemberS{Value: v.Value}
+	case *types.AttributeValueMemberN:
+		return &types.AttributeValueMemberN{Value: v.Value}
+	case *types.AttributeValueMemberB:
+		return &types.AttributeValueMemberB{Value: append([]byte{}, v.Value...)}
+	case *types.AttributeValueMemberBOOL:
+		return &types.AttributeValueMemberBOOL{Value: v.Value}
+	case *types.AttributeValueMemberNULL:
+		return &types.AttributeValueMemberNULL{Value: v.Value}
+	case *types.AttributeValueMemberSS:
+		return &types.AttributeValueMemberSS{Value: append([]string{}, v.Value...)}
+	case *types.AttributeValueMemberNS:
+		return &types.AttributeValueMemberNS{Value: append([]string{}, v.Value...)}
+	case *types.AttributeValueMemberBS:
+		bs := make([][]byte, 0, len(v.Value))
+		for _, b := range v.Value {
+			bs = append(bs, append([]byte{}, b...))
+		}
+		return &types.AttributeValueMemberBS{Value: bs}
+	case *types.AttributeValueMemberL:
+		list := make([]types.AttributeValue, 0, len(v.Value))
+		for _, elem := range v.Value {
+			list = append(list, copyValue(elem))
+		}
+		return &types.AttributeValueMemberL{Value: list}
+	case *types.AttributeValueMemberM:
+		return &types.AttributeValueMemberM{Value: copyItem(v.Value)}
+	}
+	return av
+}
+
+func parseNumber(s string) (*big.Rat, bool) {
+	return new(big.Rat).SetString(strings.TrimSpace(s))
+}
+
+func formatNumber(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	s := r.FloatString(38)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// compare orders scalar values of the same type. It reports false when values are not comparable.
+func compare(a, b types.AttributeValue) (int, bool) {
+	switch x := a.(type) {
+	case *types.AttributeValueMemberS:
+		if y, ok := b.(*types.AttributeValueMemberS); ok {
+			return strings.Compare(x.Value, y.Value), true
+		}
+	case *types.AttributeValueMemberN:
+		if y, ok := b.(*types.AttributeValueMemberN); ok {
+			nx, okx := parseNumber(x.Value)
+			ny, oky := parseNumber(y.Value)
+			if okx && oky {
+				return nx.Cmp(ny), true
+			}
+		}
+	case *types.AttributeValueMemberB:
+		if y, ok := b.(*types.AttributeValueMemberB); ok {
+			return bytes.Compare(x.Value, y.Value), true
+		}
+	}
+	return 0, false
+}
+
+func equal(a, b types.AttributeValue) bool {
+	if typeOf(a) != typeOf(b) {
+		return false
+	}
+	switch x := a.(type) {
+	case *types.AttributeValueMemberS, *types.AttributeValueMemberN, *types.AttributeValueMemberB:
+		c, ok := compare(a, b)
+		return ok && c == 0
+	case *types.AttributeValueMemberBOOL:
+		return x.Value == b.(*types.AttributeValueMemberBOOL).Value
+	case *types.AttributeValueMemberNULL:
+		return true
+	case *types.AttributeValueMemberL:
+		y := b.(*types.AttributeValueMemberL)
+		if len(x.Value) != len(y.Value) {
+			return false
+		}
+		for i := range x.Value {
+			if !equal(x.Value[i], y.Value[i]) {
+				return false
+			}
+		}
+		return true
+	case *types.AttributeValueMemberM:
+		y := b.(*types.AttributeValueMemberM)
+		if len(x.Value) != len(y.Value) {
+			return false
+		}
+		for name, v := range x.Value {
+			w, ok := y.Value[name]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	default:
+		xs, ys := setElements(a), setElements(b)
+		if len(xs) != len(ys) {
+			return false
+		}
+		for _, e := range xs {
+			if !containsElement(ys, e) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// setElements returns elements of SS, NS or BS as separate scalar values.
+func setElements(av types.AttributeValue) []types.AttributeValue {
+	var elems []types.AttributeValue
+	switch v := av.(type) {
+	case *types.AttributeValueMemberSS:
+		for _, s := range v.Value {
+			elems = append(elems, &types.AttributeValueMemberS{Value: s})
+		}
+	case *types.AttributeValueMemberNS:
+		for _, n := range v.Value {
+			elems = append(elems, &types.AttributeValueMemberN{Value: n})
+		}
+	case *types.AttributeValueMemberBS:
+		for _, b := range v.Value {
+			elems = append(elems, &types.AttributeValueMemberB{Value: b})
+		}
+	}
+	return elems
+}
+
+// newSet builds set of type `typ` (SS, NS or BS) from scalar values.
+func newSet(typ string, elems []types.AttributeValue) types.AttributeValue {
+	switch typ {
+	case "SS":
+		set := &types.AttributeValueMemberSS{}
+		for _, e := range elems {
+			set.Value = append(set.Value, e.(*types.AttributeValueMemberS).Value)
+		}
+		return set
+	case "NS":
+		set := &types.AttributeValueMemberNS{}
+		for _, e := range elems {
+			set.Value = append(set.Value, e.(*types.AttributeValueMemberN).Value)
+		}
+		return set
+	default:
+		set := &types.AttributeValueMemberBS{}
+		for _, e := range elems {
+			set.Value = append(set.Value, e.(*types.AttributeValueMemberB).Value)
+		}
+		return set
+	}
+}
+
+func containsElement(elems []types.AttributeValue, e types.AttributeValue) bool {
+	for _, x := range elems {
+		if equal(x, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSet(av types.AttributeValue) bool {
+	switch av.(type) {
+	case *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+		return true
+	}
+	return false
+}
+
+// size estimates the size of the value the same way DynamoDB does it for capacity and limits.
+func size(av types.AttributeValue) int {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return len(v.Value)
+	case *types.AttributeValueMemberN:
+		return len(strings.TrimLeft(v.Value, "-0"))/2 + 1
+	case *types.AttributeValueMemberB:
+		return len(v.Value)
+	case *types.AttributeValueMemberBOOL, *types.AttributeValueMemberNULL:
+		return 1
+	case *types.AttributeValueMemberL:
+		n := 3
+		for _, e := range v.Value {
+			n += size(e) + 1
+		}
+		return n
+	case *types.AttributeValueMemberM:
+		return 3 + itemSize(v.Value) + len(v.Value)
+	default:
+		n := 0
+		for _, e := range setElements(av) {
+			n += size(e)
+		}
+		return n
+	}
+}
+
+func itemSize(i item) int {
+	n := 0
+	for name, av := range i {
+		n += len(name) + size(av)
+	}
+	return n
+}
+
+// keyOf encodes values of the attributes into the string, that identifies the item.
+func keyOf(i item, attrs ...string) string {
+	var b strings.Builder
+	for _, attr := range attrs {
+		if attr == "" {
+			continue
+		}
+		av := i[attr]
+		if n, ok := av.(*types.AttributeValueMemberN); ok {
+			if r, ok := parseNumber(n.Value); ok {
+				av = &types.AttributeValueMemberN{Value: formatNumber(r)}
+			}
+		}
+		enc, _ := ddbjson.Marshal(av)
+		b.Write(enc)
+		b.WriteByte('|')
+	}
+	return b.String()
+}
+
+// sortedNames returns names of the map sorted alphabetically.
+func sortedNames(m map[string]types.AttributeValue) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,34 @@
+package dynamo
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"dynamodb-with-go/pkg/dynamo/memdb"
+)
+
+// InMemory is the endpoint that makes the client use in-memory DynamoDB started within the process
+// instead of a real one, e.g. DYNAMODB_ENDPOINT=memory go test ./...
+const InMemory = "memory"
+
+// memory is the in-memory DynamoDB shared by all the clients in the process, like DynamoDB local would be.
+var memory struct {
+	once sync.Once
+	url  string
+	err  error
+}
+
+func memoryEndpoint() (string, error) {
+	memory.once.Do(func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			memory.err = fmt.Errorf("could not start in-memory DynamoDB: %w", err)
+			return
+		}
+		go http.Serve(l, memdb.New())
+		memory.url = "http://" + l.Addr().String()
+	})
+	return memory.url, memory.err
+}
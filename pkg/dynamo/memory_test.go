@@ -0,0 +1,34 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemory(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := dynamo.SetupTable(t, ctx, "PartitionKeyTable", "./testdata/template.yml", dynamo.WithInMemory())
+	defer cleanup()
+
+	other, err := dynamo.NewClient(ctx, dynamo.WithEndpoint(dynamo.InMemory))
+	assert.NoError(t, err)
+
+	_, err = db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("PartitionKeyTable"),
+		Item:      map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: "1"}},
+	})
+	assert.NoError(t, err)
+
+	out, err := other.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("PartitionKeyTable"),
+		Key:       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: "1"}},
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, out.Item, "clients in the process share in-memory DynamoDB")
+}
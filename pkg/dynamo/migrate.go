@@ -0,0 +1,380 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+// MigrationPlan is the ordered list of operations, that bring existing table in line with
+// the template without recreating it, so no data is lost.
+type MigrationPlan struct {
+	Table string
+	Steps []MigrationStep
+	// Unsupported are differences, that cannot be migrated online, e.g. changed key schema
+	// or local secondary indexes, or changed type of the table key. Plan with unsupported differences
+	// cannot be applied.
+	Unsupported []Diff
+}
+
+// MigrationStep is a single operation of the plan: either UpdateTable or UpdateTimeToLive.
+// Every UpdateTable step waits until table and all its global secondary indexes are ACTIVE,
+// so that the next step can start.
+type MigrationStep struct {
+	Description      string
+	UpdateTable      *dynamodb.UpdateTableInput
+	UpdateTimeToLive *dynamodb.UpdateTimeToLiveInput
+}
+
+func (p MigrationPlan) String() string {
+	var b strings.Builder
+	switch {
+	case len(p.Unsupported) > 0:
+		fmt.Fprintf(&b, "table %q cannot be migrated, it needs to be recreated:\n", p.Table)
+		for _, d := range p.Unsupported {
+			fmt.Fprintf(&b, "  %s\n", d)
+		}
+	case len(p.Steps) == 0:
+		fmt.Fprintf(&b, "table %q is up to date\n", p.Table)
+	default:
+		fmt.Fprintf(&b, "table %q:\n", p.Table)
+		for n, step := range p.Steps {
+			fmt.Fprintf(&b, "  %d. %s\n", n+1, step.Description)
+		}
+	}
+	return b.String()
+}
+
+// PlanMigration compares table from the CloudFormation template with the existing one
+// and plans how to migrate it. Print the plan for a dry run, or pass it to ApplyMigration.
+func PlanMigration(ctx context.Context, db *dynamodb.Client, table cloudformation.AWSDynamoDBTable) (MigrationPlan, error) {
+	out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table.TableName)})
+	if err != nil {
+		return MigrationPlan{}, fmt.Errorf("could not describe table %q: %w", table.TableName, err)
+	}
+	ttl, err := db.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String(table.TableName)})
+	if err != nil {
+		return MigrationPlan{}, fmt.Errorf("could not describe time to live of table %q: %w", table.TableName, err)
+	}
+	return planMigration(table, *out.Table, ttl.TimeToLiveDescription), nil
+}
+
+// planMigration orders the steps, so that every one of them is valid on its own: removed indexes
+// are deleted first, then billing mode and throughput are changed, and only then new indexes
+// are created, one at a time, as DynamoDB allows.
+func planMigration(table cloudformation.AWSDynamoDBTable, desc types.TableDescription, ttl *types.TimeToLiveDescription) MigrationPlan {
+	want := FromCloudFormationToCreateInput(table)
+	plan := MigrationPlan{Table: table.TableName}
+	name := aws.String(table.TableName)
+
+	var deletes, creates []string
+	attributesChanged := false
+	for _, d := range Drift(table, desc, ttl) {
+		switch {
+		case d.Kind == DiffAttributeDefinitions:
+			attributesChanged = true
+		case d.Kind == DiffKeySchema && d.Index == "", d.Index != "" && !d.Global:
+			plan.Unsupported = append(plan.Unsupported, d)
+		case d.Kind == DiffMissingIndex:
+			creates = append(creates, d.Index)
+		case d.Kind == DiffExtraIndex:
+			deletes = append(deletes, d.Index)
+		case d.Kind == DiffKeySchema, d.Kind == DiffProjection:
+			// Index can't be changed, it's rebuilt from the table instead.
+			deletes = append(deletes, d.Index)
+			creates = append(creates, d.Index)
+		}
+	}
+	if attributesChanged {
+		rebuilt, unsupported := attributeChanges(want, desc)
+		plan.Unsupported = append(plan.Unsupported, unsupported...)
+		deletes = appendMissing(deletes, rebuilt...)
+		creates = appendMissing(creates, rebuilt...)
+	}
+
+	for _, idx := range deletes {
+		plan.Steps = append(plan.Steps, MigrationStep{
+			Description: fmt.Sprintf("delete global index %q", idx),
+			UpdateTable: &dynamodb.UpdateTableInput{
+				TableName: name,
+				GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{{
+					Delete: &types.DeleteGlobalSecondaryIndexAction{IndexName: aws.String(idx)},
+				}},
+			},
+		})
+	}
+
+	wantGlobal := map[string]types.GlobalSecondaryIndex{}
+	for _, idx := range want.GlobalSecondaryIndexes {
+		wantGlobal[aws.ToString(idx.IndexName)] = idx
+	}
+	recreated := map[string]bool{}
+	for _, idx := range creates {
+		recreated[idx] = true
+	}
+	// throughputUpdates sets throughput of the indexes, that stay in place.
+	throughputUpdates := func(onlyChanged bool) []types.GlobalSecondaryIndexUpdate {
+		var updates []types.GlobalSecondaryIndexUpdate
+		for _, idx := range desc.GlobalSecondaryIndexes {
+			w, ok := wantGlobal[aws.ToString(idx.IndexName)]
+			if !ok || recreated[aws.ToString(idx.IndexName)] {
+				continue
+			}
+			if onlyChanged && formatThroughput(w.ProvisionedThroughput) == formatThroughputDescription(idx.ProvisionedThroughput) {
+				continue
+			}
+			updates = append(updates, types.GlobalSecondaryIndexUpdate{
+				Update: &types.UpdateGlobalSecondaryIndexAction{
+					IndexName:             idx.IndexName,
+					ProvisionedThroughput: w.ProvisionedThroughput,
+				},
+			})
+		}
+		return updates
+	}
+
+	gotBilling := billingModeFromDescription(desc)
+	switch {
+	case want.BillingMode != gotBilling && want.BillingMode == types.BillingModePayPerRequest:
+		plan.Steps = append(plan.Steps, MigrationStep{
+			Description: "switch billing mode to PAY_PER_REQUEST",
+			UpdateTable: &dynamodb.UpdateTableInput{TableName: name, BillingMode: want.BillingMode},
+		})
+	case want.BillingMode != gotBilling:
+		plan.Steps = append(plan.Steps, MigrationStep{
+			Description: fmt.Sprintf("switch billing mode to PROVISIONED with %s", formatThroughput(want.ProvisionedThroughput)),
+			UpdateTable: &dynamodb.UpdateTableInput{
+				TableName:                   name,
+				BillingMode:                 want.BillingMode,
+				ProvisionedThroughput:       want.ProvisionedThroughput,
+				GlobalSecondaryIndexUpdates: throughputUpdates(false),
+			},
+		})
+	case want.BillingMode == types.BillingModeProvisioned:
+		update := &dynamodb.UpdateTableInput{TableName: name, GlobalSecondaryIndexUpdates: throughputUpdates(true)}
+		if formatThroughput(want.ProvisionedThroughput) != formatThroughputDescription(desc.ProvisionedThroughput) {
+			update.ProvisionedThroughput = want.ProvisionedThroughput
+		}
+		if update.ProvisionedThroughput != nil || len(update.GlobalSecondaryIndexUpdates) > 0 {
+			plan.Steps = append(plan.Steps, MigrationStep{Description: "update provisioned throughput", UpdateTable: update})
+		}
+	}
+
+	for _, idx := range creates {
+		gsi := wantGlobal[idx]
+		plan.Steps = append(plan.Steps, MigrationStep{
+			Description: fmt.Sprintf("create global index %q %s and wait for backfill", idx, indexSchema{gsi.KeySchema, gsi.Projection}),
+			UpdateTable: &dynamodb.UpdateTableInput{
+				TableName:            name,
+				AttributeDefinitions: want.AttributeDefinitions,
+				GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{{
+					Create: &types.CreateGlobalSecondaryIndexAction{
+						IndexName:             gsi.IndexName,
+						KeySchema:             gsi.KeySchema,
+						Projection:            gsi.Projection,
+						ProvisionedThroughput: gsi.ProvisionedThroughput,
+					},
+				}},
+			},
+		})
+	}
+
+	plan.Steps = append(plan.Steps, streamSteps(name, want.StreamSpecification, desc.StreamSpecification)...)
+	plan.Steps = append(plan.Steps, timeToLiveSteps(name, table.TimeToLiveSpecification, ttl)...)
+	return plan
+}
+
+// attributeChanges maps changed attribute definitions to global indexes, that have to be rebuilt
+// with the new definitions. Attributes of the table and local index keys can't be changed, and
+// definitions no global index uses can't be migrated either, so they are returned as unsupported.
+func attributeChanges(want dynamodb.CreateTableInput, desc types.TableDescription) (rebuilt []string, unsupported []Diff) {
+	wantTypes, gotTypes := map[string]types.ScalarAttributeType{}, map[string]types.ScalarAttributeType{}
+	var names []string
+	for _, attr := range want.AttributeDefinitions {
+		wantTypes[aws.ToString(attr.AttributeName)] = attr.AttributeType
+		names = append(names, aws.ToString(attr.AttributeName))
+	}
+	for _, attr := range desc.AttributeDefinitions {
+		gotTypes[aws.ToString(attr.AttributeName)] = attr.AttributeType
+		if _, ok := wantTypes[aws.ToString(attr.AttributeName)]; !ok {
+			names = append(names, aws.ToString(attr.AttributeName))
+		}
+	}
+	sort.Strings(names)
+
+	fixed := map[string]bool{}
+	addKeys := func(keys []types.KeySchemaElement) {
+		for _, k := range keys {
+			fixed[aws.ToString(k.AttributeName)] = true
+		}
+	}
+	addKeys(want.KeySchema)
+	addKeys(desc.KeySchema)
+	for _, idx := range want.LocalSecondaryIndexes {
+		addKeys(idx.KeySchema)
+	}
+	for _, idx := range desc.LocalSecondaryIndexes {
+		addKeys(idx.KeySchema)
+	}
+
+	// wantGlobal and gotGlobal list global indexes keyed on the attribute.
+	wantGlobal, gotGlobal := map[string][]string{}, map[string][]string{}
+	kept := map[string]bool{}
+	for _, idx := range want.GlobalSecondaryIndexes {
+		kept[aws.ToString(idx.IndexName)] = true
+		for _, k := range idx.KeySchema {
+			wantGlobal[aws.ToString(k.AttributeName)] = append(wantGlobal[aws.ToString(k.AttributeName)], aws.ToString(idx.IndexName))
+		}
+	}
+	for _, idx := range desc.GlobalSecondaryIndexes {
+		for _, k := range idx.KeySchema {
+			gotGlobal[aws.ToString(k.AttributeName)] = append(gotGlobal[aws.ToString(k.AttributeName)], aws.ToString(idx.IndexName))
+		}
+	}
+
+	for _, name := range names {
+		w, g := wantTypes[name], gotTypes[name]
+		if w == g {
+			continue
+		}
+		var mapped bool
+		switch {
+		case fixed[name]:
+		case w != "" && g != "":
+			// Index keeps the type of its key attributes, so the indexes using the attribute are rebuilt.
+			for _, idx := range gotGlobal[name] {
+				if kept[idx] {
+					rebuilt = append(rebuilt, idx)
+				}
+			}
+			mapped = len(wantGlobal[name]) > 0 || len(gotGlobal[name]) > 0
+		case w != "":
+			mapped = len(wantGlobal[name]) > 0
+		default:
+			mapped = len(gotGlobal[name]) > 0
+		}
+		if !mapped {
+			unsupported = append(unsupported, Diff{Kind: DiffAttributeDefinitions, Want: formatAttribute(name, w), Got: formatAttribute(name, g)})
+		}
+	}
+	return rebuilt, unsupported
+}
+
+func formatAttribute(name string, typ types.ScalarAttributeType) string {
+	if typ == "" {
+		return "none"
+	}
+	return name + " " + string(typ)
+}
+
+// appendMissing appends names, that are not in the list yet.
+func appendMissing(list []string, names ...string) []string {
+	for _, name := range names {
+		found := false
+		for _, n := range list {
+			found = found || n == name
+		}
+		if !found {
+			list = append(list, name)
+		}
+	}
+	return list
+}
+
+// streamSteps changes the stream. Stream view type can't be changed in place,
+// stream is disabled and enabled again instead.
+func streamSteps(name *string, want, got *types.StreamSpecification) []MigrationStep {
+	wantEnabled := want != nil && aws.ToBool(want.StreamEnabled)
+	gotEnabled := got != nil && aws.ToBool(got.StreamEnabled)
+	var steps []MigrationStep
+	if gotEnabled && (!wantEnabled || want.StreamViewType != got.StreamViewType) {
+		steps = append(steps, MigrationStep{
+			Description: "disable stream",
+			UpdateTable: &dynamodb.UpdateTableInput{
+				TableName:           name,
+				StreamSpecification: &types.StreamSpecification{StreamEnabled: aws.Bool(false)},
+			},
+		})
+	}
+	if wantEnabled && (!gotEnabled || want.StreamViewType != got.StreamViewType) {
+		steps = append(steps, MigrationStep{
+			Description: fmt.Sprintf("enable stream %s", want.StreamViewType),
+			UpdateTable: &dynamodb.UpdateTableInput{TableName: name, StreamSpecification: want},
+		})
+	}
+	return steps
+}
+
+// timeToLiveSteps changes time to live, disabling it first when it's moved to other attribute.
+func timeToLiveSteps(name *string, want *cloudformation.AWSDynamoDBTable_TimeToLiveSpecification, got *types.TimeToLiveDescription) []MigrationStep {
+	wantEnabled := want != nil && want.Enabled
+	gotEnabled := got != nil && (got.TimeToLiveStatus == types.TimeToLiveStatusEnabled || got.TimeToLiveStatus == types.TimeToLiveStatusEnabling)
+	var steps []MigrationStep
+	if gotEnabled && (!wantEnabled || want.AttributeName != aws.ToString(got.AttributeName)) {
+		steps = append(steps, MigrationStep{
+			Description: "disable time to live",
+			UpdateTimeToLive: &dynamodb.UpdateTimeToLiveInput{
+				TableName: name,
+				TimeToLiveSpecification: &types.TimeToLiveSpecification{
+					AttributeName: got.AttributeName,
+					Enabled:       aws.Bool(false),
+				},
+			},
+		})
+	}
+	if wantEnabled && (!gotEnabled || want.AttributeName != aws.ToString(got.AttributeName)) {
+		steps = append(steps, MigrationStep{
+			Description: fmt.Sprintf("enable time to live on %q", want.AttributeName),
+			UpdateTimeToLive: &dynamodb.UpdateTimeToLiveInput{
+				TableName: name,
+				TimeToLiveSpecification: &types.TimeToLiveSpecification{
+					AttributeName: aws.String(want.AttributeName),
+					Enabled:       aws.Bool(true),
+				},
+			},
+		})
+	}
+	return steps
+}
+
+// ApplyMigration executes steps of the plan one by one, waiting for the table and its global
+// secondary indexes to become ACTIVE after each of them. Plan with unsupported differences
+// is rejected with SchemaMismatchError.
+func ApplyMigration(ctx context.Context, db *dynamodb.Client, plan MigrationPlan, opts ...Option) error {
+	o := newOptions(opts)
+	if len(plan.Unsupported) > 0 {
+		return &SchemaMismatchError{Table: plan.Table, Differences: plan.Unsupported}
+	}
+	for n, step := range plan.Steps {
+		var err error
+		if step.UpdateTable != nil {
+			_, err = db.UpdateTable(ctx, step.UpdateTable)
+			if err == nil {
+				_, err = waitUntilActive(ctx, db, plan.Table, o.timeout)
+			}
+		}
+		if step.UpdateTimeToLive != nil {
+			_, err = db.UpdateTimeToLive(ctx, step.UpdateTimeToLive)
+		}
+		if err != nil {
+			return fmt.Errorf("migration of table %q failed at step %d (%s): %w", plan.Table, n+1, step.Description, err)
+		}
+	}
+	return nil
+}
+
+// Migrate brings existing table in line with the table from the CloudFormation template
+// and returns the plan it applied.
+func Migrate(ctx context.Context, db *dynamodb.Client, table cloudformation.AWSDynamoDBTable, opts ...Option) (MigrationPlan, error) {
+	plan, err := PlanMigration(ctx, db, table)
+	if err != nil {
+		return plan, err
+	}
+	return plan, ApplyMigration(ctx, db, plan, opts...)
+}
@@ -0,0 +1,137 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := dynamo.SetupTable(t, ctx, "SensorsTable", "../../episode8/v1/template.yml")
+	defer cleanup()
+	_, err := db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("SensorsTable"),
+		Item: map[string]types.AttributeValue{
+			"pk":     &types.AttributeValueMemberS{Value: "SENSOR#1"},
+			"sk":     &types.AttributeValueMemberS{Value: "SENSOR"},
+			"gsi_pk": &types.AttributeValueMemberS{Value: "CITY#Poznan"},
+			"gsi_sk": &types.AttributeValueMemberS{Value: "SENSOR#1"},
+		},
+	})
+	assert.NoError(t, err)
+
+	v2, err := dynamo.LoadTable("../../episode8/v2/template.yml", "SensorsTable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := dynamo.PlanMigration(ctx, db, v2)
+	assert.NoError(t, err)
+	assert.Equal(t, "table \"SensorsTable\":\n"+
+		"  1. create global index \"ByLocation\" [gsi_pk HASH, gsi_sk RANGE] ALL and wait for backfill\n", plan.String())
+
+	err = dynamo.ApplyMigration(ctx, db, plan)
+	assert.NoError(t, err)
+
+	out, err := db.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String("SensorsTable"),
+		IndexName:                 aws.String("ByLocation"),
+		KeyConditionExpression:    aws.String("gsi_pk = :city"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":city": &types.AttributeValueMemberS{Value: "CITY#Poznan"}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, out.Items, 1, "existing items are backfilled into the new index")
+
+	diffs, err := dynamo.DetectDrift(ctx, db, v2)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	t.Run("changes", func(t *testing.T) {
+		changed := v2
+		changed.GlobalSecondaryIndexes = append([]cloudformation.AWSDynamoDBTable_GlobalSecondaryIndex{}, v2.GlobalSecondaryIndexes...)
+		changed.GlobalSecondaryIndexes[0].Projection = &cloudformation.AWSDynamoDBTable_Projection{ProjectionType: "KEYS_ONLY"}
+		changed.BillingMode = "PROVISIONED"
+		changed.ProvisionedThroughput = &cloudformation.AWSDynamoDBTable_ProvisionedThroughput{ReadCapacityUnits: 5, WriteCapacityUnits: 5}
+		changed.GlobalSecondaryIndexes[0].ProvisionedThroughput = &cloudformation.AWSDynamoDBTable_ProvisionedThroughput{ReadCapacityUnits: 2, WriteCapacityUnits: 2}
+		changed.StreamSpecification = &cloudformation.AWSDynamoDBTable_StreamSpecification{StreamViewType: "NEW_IMAGE"}
+		changed.TimeToLiveSpecification = &cloudformation.AWSDynamoDBTable_TimeToLiveSpecification{AttributeName: "expires_at", Enabled: true}
+
+		plan, err := dynamo.Migrate(ctx, db, changed)
+		assert.NoError(t, err)
+		assert.Equal(t, "table \"SensorsTable\":\n"+
+			"  1. delete global index \"ByLocation\"\n"+
+			"  2. switch billing mode to PROVISIONED with 5 RCU, 5 WCU\n"+
+			"  3. create global index \"ByLocation\" [gsi_pk HASH, gsi_sk RANGE] KEYS_ONLY and wait for backfill\n"+
+			"  4. enable stream NEW_IMAGE\n"+
+			"  5. enable time to live on \"expires_at\"\n", plan.String())
+
+		diffs, err := dynamo.DetectDrift(ctx, db, changed)
+		assert.NoError(t, err)
+		assert.Empty(t, diffs)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		changed := v2
+		changed.KeySchema = changed.KeySchema[:1]
+
+		_, err := dynamo.Migrate(ctx, db, changed)
+		var mismatch *dynamo.SchemaMismatchError
+		if assert.True(t, errors.As(err, &mismatch)) {
+			assert.Equal(t, dynamo.DiffKeySchema, mismatch.Differences[0].Kind)
+		}
+	})
+}
+
+func TestMigrateAttributeDefinitions(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := dynamo.SetupTable(t, ctx, "SensorsTable", "../../episode8/v2/template.yml")
+	defer cleanup()
+	v2, err := dynamo.LoadTable("../../episode8/v2/template.yml", "SensorsTable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("index key type", func(t *testing.T) {
+		changed := v2
+		changed.AttributeDefinitions = append([]cloudformation.AWSDynamoDBTable_AttributeDefinition{}, v2.AttributeDefinitions...)
+		for i, attr := range changed.AttributeDefinitions {
+			if attr.AttributeName == "gsi_pk" {
+				changed.AttributeDefinitions[i].AttributeType = "N"
+			}
+		}
+
+		plan, err := dynamo.Migrate(ctx, db, changed)
+		assert.NoError(t, err)
+		assert.Equal(t, "table \"SensorsTable\":\n"+
+			"  1. delete global index \"ByLocation\"\n"+
+			"  2. create global index \"ByLocation\" [gsi_pk HASH, gsi_sk RANGE] ALL and wait for backfill\n", plan.String())
+
+		diffs, err := dynamo.DetectDrift(ctx, db, changed)
+		assert.NoError(t, err)
+		assert.Empty(t, diffs)
+	})
+
+	t.Run("table key type", func(t *testing.T) {
+		changed := v2
+		changed.AttributeDefinitions = append([]cloudformation.AWSDynamoDBTable_AttributeDefinition{}, v2.AttributeDefinitions...)
+		for i, attr := range changed.AttributeDefinitions {
+			if attr.AttributeName == "pk" {
+				changed.AttributeDefinitions[i].AttributeType = "N"
+			}
+		}
+
+		plan, err := dynamo.PlanMigration(ctx, db, changed)
+		assert.NoError(t, err)
+		assert.Equal(t, []dynamo.Diff{{Kind: dynamo.DiffAttributeDefinitions, Want: "pk N", Got: "pk S"}}, plan.Unsupported)
+		_, err = dynamo.Migrate(ctx, db, changed)
+		var mismatch *dynamo.SchemaMismatchError
+		assert.True(t, errors.As(err, &mismatch))
+	})
+}
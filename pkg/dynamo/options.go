@@ -0,0 +1,180 @@
+package dynamo
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/google/uuid"
+)
+
+const defaultEndpoint = "http://localhost:8000"
+
+// Environment variables configuring connection to the DynamoDB. Options passed explicitly take precedence.
+const (
+	EnvEndpoint        = "DYNAMODB_ENDPOINT"
+	EnvRegion          = "DYNAMODB_REGION"
+	EnvAccessKeyID     = "DYNAMODB_ACCESS_KEY_ID"
+	EnvSecretAccessKey = "DYNAMODB_SECRET_ACCESS_KEY"
+	EnvConnectTimeout  = "DYNAMODB_CONNECT_TIMEOUT"
+)
+
+// Option configures SetupTable, SetupTables, LoadTables and NewClient. Paginators, batches and scans
+// have their own options: PaginatorOption, BatchOption and ScanOption.
+type Option func(*options)
+
+type options struct {
+	endpoint        string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	connectTimeout  time.Duration
+	// endpointSet tells whether endpoint was configured explicitly, or is the default one.
+	endpointSet bool
+
+	timeout    time.Duration
+	names      map[string]string
+	fixtures   []string
+	parameters map[string]string
+
+	err error
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		endpoint:        defaultEndpoint,
+		region:          "local",
+		accessKeyID:     "local",
+		secretAccessKey: "local",
+		connectTimeout:  1500 * time.Millisecond,
+		timeout:         2 * time.Minute,
+	}
+	o.fromEnv()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (o *options) fromEnv() {
+	if v := os.Getenv(EnvEndpoint); v != "" {
+		o.endpoint = v
+		o.endpointSet = true
+	}
+	if v := os.Getenv(EnvRegion); v != "" {
+		o.region = v
+	}
+	if v := os.Getenv(EnvAccessKeyID); v != "" {
+		o.accessKeyID = v
+	}
+	if v := os.Getenv(EnvSecretAccessKey); v != "" {
+		o.secretAccessKey = v
+	}
+	if v := os.Getenv(EnvConnectTimeout); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			o.err = fmt.Errorf("invalid %s: %w", EnvConnectTimeout, err)
+			return
+		}
+		o.connectTimeout = d
+	}
+}
+
+// WithEndpoint sets URL of the DynamoDB, e.g. http://dynamodb:8000 for DynamoDB local running
+// in CI or http://localhost:4566 for LocalStack. Default is http://localhost:8000.
+// Use InMemory to run against in-memory DynamoDB.
+func WithEndpoint(url string) Option {
+	return func(o *options) {
+		o.endpoint = url
+		o.endpointSet = true
+	}
+}
+
+// WithInMemory makes the client use in-memory DynamoDB started within the process.
+// It's the same as WithEndpoint(InMemory).
+func WithInMemory() Option {
+	return WithEndpoint(InMemory)
+}
+
+// WithRegion sets region of the DynamoDB client. Default is "local".
+func WithRegion(region string) Option {
+	return func(o *options) {
+		o.region = region
+	}
+}
+
+// WithCredentials sets static credentials of the DynamoDB client. Default is "local"/"local".
+func WithCredentials(accessKeyID, secretAccessKey string) Option {
+	return func(o *options) {
+		o.accessKeyID = accessKeyID
+		o.secretAccessKey = secretAccessKey
+	}
+}
+
+// WithConnectTimeout sets how long to wait for DynamoDB to respond when checking
+// that it can be reached. Default is 1.5s.
+func WithConnectTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.connectTimeout = d
+	}
+}
+
+// WithTimeout sets how long to wait for tables (and their global secondary indexes)
+// to become ACTIVE, and for deleted tables to disappear. Default is 2 minutes.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithUniqueNames gives every created table unique physical name, made of the table name,
+// name of the test and random suffix. This way tests calling t.Parallel() can share one DynamoDB instance.
+// Physical names are stored in `names` under logical names of the tables from the template.
+func WithUniqueNames(names map[string]string) Option {
+	return func(o *options) {
+		o.names = names
+	}
+}
+
+// WithParameters overrides default values of the template parameters. Pseudo parameters,
+// e.g. AWS::Region or AWS::StackName, can be set too.
+func WithParameters(params map[string]string) Option {
+	return func(o *options) {
+		if o.parameters == nil {
+			o.parameters = map[string]string{}
+		}
+		for k, v := range params {
+			o.parameters[k] = v
+		}
+	}
+}
+
+// WithFixtures loads items from the fixture files into the tables once they are ACTIVE.
+// See ReadFixtures for supported formats. With SetupTables, fixtures are keyed by logical
+// names of the tables, unless the template defines only one table.
+func WithFixtures(paths ...string) Option {
+	return func(o *options) {
+		o.fixtures = append(o.fixtures, paths...)
+	}
+}
+
+const maxTableNameLength = 255
+
+var invalidTableNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// rename replaces physical name of the table when unique names were requested.
+func (o options) rename(t testing.TB, logical string, table *cloudformation.AWSDynamoDBTable) {
+	if o.names == nil {
+		return
+	}
+	suffix := "-" + uuid.New().String()[:8]
+	name := table.TableName + "-" + invalidTableNameChars.ReplaceAllString(t.Name(), "_")
+	if len(name)+len(suffix) > maxTableNameLength {
+		name = name[:maxTableNameLength-len(suffix)]
+	}
+	table.TableName = name + suffix
+	o.names[logical] = table.TableName
+}
@@ -0,0 +1,166 @@
+package dynamo
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Page is one response of Query or Scan.
+type Page struct {
+	Items            []map[string]types.AttributeValue
+	Count            int32
+	ScannedCount     int32
+	LastEvaluatedKey map[string]types.AttributeValue
+	ConsumedCapacity *types.ConsumedCapacity
+}
+
+// Decode unmarshals items of the page into out, pointer to the slice of structs or maps.
+func (p Page) Decode(out interface{}) error {
+	return attributevalue.UnmarshalListOfMaps(p.Items, out)
+}
+
+// Paginator pages through results of Query or Scan, following LastEvaluatedKey until
+// there are no more items, or the limit set with WithLimit is reached.
+type Paginator struct {
+	read     func(ctx context.Context, startKey map[string]types.AttributeValue, limit *int32) (Page, error)
+	pageSize *int32
+	limit    int
+	count    int
+	startKey map[string]types.AttributeValue
+	done     bool
+}
+
+// PaginatorOption configures NewQueryPaginator and NewScanPaginator.
+type PaginatorOption func(*Paginator)
+
+// WithLimit stops the paginator after n items. Zero, the default, reads all of them.
+func WithLimit(n int) PaginatorOption {
+	return func(p *Paginator) {
+		p.limit = n
+	}
+}
+
+// NewQueryPaginator creates Paginator for the query. Limit of the input sets the page size,
+// ExclusiveStartKey where the first page starts. The input is not modified.
+func NewQueryPaginator(db Client, params *dynamodb.QueryInput, opts ...PaginatorOption) *Paginator {
+	in := *params
+	p := newPaginator(in.ExclusiveStartKey, in.Limit, opts)
+	p.read = func(ctx context.Context, startKey map[string]types.AttributeValue, limit *int32) (Page, error) {
+		in.ExclusiveStartKey, in.Limit = startKey, limit
+		out, err := db.Query(ctx, &in)
+		if err != nil {
+			return Page{}, err
+		}
+		return Page{
+			Items:            out.Items,
+			Count:            out.Count,
+			ScannedCount:     out.ScannedCount,
+			LastEvaluatedKey: out.LastEvaluatedKey,
+			ConsumedCapacity: out.ConsumedCapacity,
+		}, nil
+	}
+	return p
+}
+
+// NewScanPaginator creates Paginator for the scan, the same way NewQueryPaginator does for the query.
+func NewScanPaginator(db Client, params *dynamodb.ScanInput, opts ...PaginatorOption) *Paginator {
+	in := *params
+	p := newPaginator(in.ExclusiveStartKey, in.Limit, opts)
+	p.read = func(ctx context.Context, startKey map[string]types.AttributeValue, limit *int32) (Page, error) {
+		in.ExclusiveStartKey, in.Limit = startKey, limit
+		out, err := db.Scan(ctx, &in)
+		if err != nil {
+			return Page{}, err
+		}
+		return Page{
+			Items:            out.Items,
+			Count:            out.Count,
+			ScannedCount:     out.ScannedCount,
+			LastEvaluatedKey: out.LastEvaluatedKey,
+			ConsumedCapacity: out.ConsumedCapacity,
+		}, nil
+	}
+	return p
+}
+
+func newPaginator(startKey map[string]types.AttributeValue, pageSize *int32, opts []PaginatorOption) *Paginator {
+	p := &Paginator{startKey: startKey, pageSize: pageSize}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// HasMorePages tells whether NextPage can be called.
+func (p *Paginator) HasMorePages() bool {
+	return !p.done
+}
+
+// LastEvaluatedKey returns the key, where the next page starts. It is nil once all pages were read.
+// When the limit was reached, it can be passed as ExclusiveStartKey to continue later.
+func (p *Paginator) LastEvaluatedKey() map[string]types.AttributeValue {
+	return p.startKey
+}
+
+// NextPage reads the next page. When the limit is set, requests are limited to the number
+// of items still missing, so pages never go past it and LastEvaluatedKey points right after
+// the last returned item.
+func (p *Paginator) NextPage(ctx context.Context) (Page, error) {
+	if p.done {
+		return Page{}, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return Page{}, err
+	}
+	limit := p.pageSize
+	if p.limit > 0 {
+		if left := int32(p.limit - p.count); limit == nil || *limit > left {
+			limit = &left
+		}
+	}
+	page, err := p.read(ctx, p.startKey, limit)
+	if err != nil {
+		return Page{}, err
+	}
+	p.count += len(page.Items)
+	p.startKey = page.LastEvaluatedKey
+	p.done = len(page.LastEvaluatedKey) == 0 || (p.limit > 0 && p.count >= p.limit)
+	return page, nil
+}
+
+// EachPage calls fn with every page, until there are no more pages or fn returns an error.
+func (p *Paginator) EachPage(ctx context.Context, fn func(page Page) error) error {
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Items reads all remaining pages and returns their items.
+func (p *Paginator) Items(ctx context.Context) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	err := p.EachPage(ctx, func(page Page) error {
+		items = append(items, page.Items...)
+		return nil
+	})
+	return items, err
+}
+
+// All reads all remaining pages and unmarshals their items into out, pointer to the slice
+// of structs or maps.
+func (p *Paginator) All(ctx context.Context, out interface{}) error {
+	items, err := p.Items(ctx)
+	if err != nil {
+		return err
+	}
+	return attributevalue.UnmarshalListOfMaps(items, out)
+}
@@ -0,0 +1,149 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type pageItem struct {
+	PK   string `dynamodbav:"pk"`
+	SK   string `dynamodbav:"sk"`
+	Even bool   `dynamodbav:"even"`
+}
+
+func setupPages(t *testing.T, ctx context.Context) (*dynamodb.Client, string, func()) {
+	names := map[string]string{}
+	db, cleanup := dynamo.SetupTable(t, ctx, "CompositePrimaryKeyTable", "./testdata/template.yml", dynamo.WithUniqueNames(names))
+	table := names["CompositePrimaryKeyTable"]
+	for _, pk := range []string{"1", "2"} {
+		for i := 0; i < 25; i++ {
+			_, err := db.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName: aws.String(table),
+				Item: map[string]types.AttributeValue{
+					"pk":   &types.AttributeValueMemberS{Value: pk},
+					"sk":   &types.AttributeValueMemberS{Value: fmt.Sprintf("%02d", i)},
+					"even": &types.AttributeValueMemberBOOL{Value: i%2 == 0},
+				},
+			})
+			assert.NoError(t, err)
+		}
+	}
+	return db, table, cleanup
+}
+
+func queryInput(table string) *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
+		KeyConditionExpression:    aws.String("pk = :pk"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":pk": &types.AttributeValueMemberS{Value: "1"}},
+		TableName:                 aws.String(table),
+		Limit:                     aws.Int32(10),
+	}
+}
+
+func TestQueryPaginator(t *testing.T) {
+	ctx := context.Background()
+	db, table, cleanup := setupPages(t, ctx)
+	defer cleanup()
+
+	t.Run("read all pages", func(t *testing.T) {
+		var sizes []int
+		var items []pageItem
+		err := dynamo.NewQueryPaginator(db, queryInput(table)).EachPage(ctx, func(page dynamo.Page) error {
+			sizes = append(sizes, len(page.Items))
+			var decoded []pageItem
+			if err := page.Decode(&decoded); err != nil {
+				return err
+			}
+			items = append(items, decoded...)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{10, 10, 5}, sizes)
+		assert.Len(t, items, 25)
+		for i, item := range items {
+			assert.Equal(t, pageItem{PK: "1", SK: fmt.Sprintf("%02d", i), Even: i%2 == 0}, item)
+		}
+	})
+
+	t.Run("stop at the limit and continue later", func(t *testing.T) {
+		in := queryInput(table)
+		in.Limit = aws.Int32(5)
+		p := dynamo.NewQueryPaginator(db, in, dynamo.WithLimit(12))
+		var items []pageItem
+		assert.NoError(t, p.All(ctx, &items))
+		assert.Len(t, items, 12)
+		assert.False(t, p.HasMorePages())
+		assert.Equal(t, &types.AttributeValueMemberS{Value: "11"}, p.LastEvaluatedKey()["sk"])
+		assert.Equal(t, int32(5), *in.Limit, "input should not be modified")
+
+		in.ExclusiveStartKey = p.LastEvaluatedKey()
+		var rest []pageItem
+		assert.NoError(t, dynamo.NewQueryPaginator(db, in).All(ctx, &rest))
+		assert.Len(t, rest, 13)
+		assert.Equal(t, "12", rest[0].SK)
+	})
+
+	t.Run("reach the limit through filtered pages", func(t *testing.T) {
+		in := queryInput(table)
+		in.FilterExpression = aws.String("even = :even")
+		in.ExpressionAttributeValues[":even"] = &types.AttributeValueMemberBOOL{Value: true}
+		var items []pageItem
+		assert.NoError(t, dynamo.NewQueryPaginator(db, in, dynamo.WithLimit(8)).All(ctx, &items))
+		assert.Len(t, items, 8)
+		assert.Equal(t, "14", items[7].SK)
+	})
+
+	t.Run("stop when callback fails", func(t *testing.T) {
+		failed := errors.New("failed")
+		calls := 0
+		err := dynamo.NewQueryPaginator(db, queryInput(table)).EachPage(ctx, func(page dynamo.Page) error {
+			calls++
+			return failed
+		})
+		assert.Equal(t, failed, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("stop when context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		p := dynamo.NewQueryPaginator(db, queryInput(table))
+		_, err := p.NextPage(ctx)
+		assert.NoError(t, err)
+		cancel()
+		_, err = p.NextPage(ctx)
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.True(t, p.HasMorePages())
+	})
+}
+
+func TestScanPaginator(t *testing.T) {
+	ctx := context.Background()
+	db, table, cleanup := setupPages(t, ctx)
+	defer cleanup()
+
+	pages := 0
+	count := 0
+	err := dynamo.NewScanPaginator(db, &dynamodb.ScanInput{
+		TableName: aws.String(table),
+		Limit:     aws.Int32(7),
+	}).EachPage(ctx, func(page dynamo.Page) error {
+		pages++
+		count += len(page.Items)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 50, count)
+	assert.Equal(t, 8, pages)
+
+	items, err := dynamo.NewScanPaginator(db, &dynamodb.ScanInput{TableName: aws.String(table)}, dynamo.WithLimit(30)).Items(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, items, 30)
+}
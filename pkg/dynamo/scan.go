@@ -0,0 +1,283 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"dynamodb-with-go/pkg/dynamo/ddbjson"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Checkpoint is the position of the segment of ParallelScan. It can be stored, e.g. encoded as JSON,
+// and passed to Resume to continue the scan later.
+type Checkpoint struct {
+	Segment          int
+	LastEvaluatedKey ddbjson.Item `json:",omitempty"`
+	Done             bool
+}
+
+// ScanProgress tells how far ParallelScan got since Run started.
+type ScanProgress struct {
+	Items            int64
+	ScannedItems     int64
+	ConsumedCapacity float64
+	SegmentsDone     int
+	TotalSegments    int
+	Elapsed          time.Duration
+}
+
+// ItemsPerSecond returns the average number of items returned per second.
+func (p ScanProgress) ItemsPerSecond() float64 {
+	return perSecond(float64(p.Items), p.Elapsed)
+}
+
+// CapacityPerSecond returns the average number of read capacity units consumed per second.
+func (p ScanProgress) CapacityPerSecond() float64 {
+	return perSecond(p.ConsumedCapacity, p.Elapsed)
+}
+
+func (p ScanProgress) String() string {
+	return fmt.Sprintf("%d/%d segments, %d items (%.1f/s), %.1f capacity units (%.1f/s)",
+		p.SegmentsDone, p.TotalSegments, p.Items, p.ItemsPerSecond(), p.ConsumedCapacity, p.CapacityPerSecond())
+}
+
+func perSecond(v float64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return v / d.Seconds()
+}
+
+// ParallelScan scans the table, or the index, split into segments, that are read concurrently
+// (see WithScanConcurrency). Checkpoints of the segments move forward once their pages are handled,
+// so the scan stopped by an error or canceled context can continue from where it stopped.
+type ParallelScan struct {
+	db               Client
+	in               dynamodb.ScanInput
+	concurrency      int
+	report           func(ScanProgress)
+	progressInterval time.Duration
+
+	mu          sync.Mutex
+	checkpoints []Checkpoint
+	progress    ScanProgress
+	started     time.Time
+	stopped     time.Time
+}
+
+// ScanOption configures NewParallelScan.
+type ScanOption func(*ParallelScan)
+
+// WithScanConcurrency sets how many segments ParallelScan reads at once. Default is 4.
+func WithScanConcurrency(n int) ScanOption {
+	return func(s *ParallelScan) {
+		s.concurrency = n
+	}
+}
+
+// WithProgress makes ParallelScan report its progress every interval, and once the scan stops.
+func WithProgress(interval time.Duration, fn func(ScanProgress)) ScanOption {
+	return func(s *ParallelScan) {
+		s.progressInterval = interval
+		s.report = fn
+	}
+}
+
+// NewParallelScan creates scan of the input split into the number of segments. Limit of the input
+// sets the page size. ReturnConsumedCapacity is set to TOTAL, unless set otherwise.
+func NewParallelScan(db Client, params *dynamodb.ScanInput, segments int, opts ...ScanOption) *ParallelScan {
+	s := &ParallelScan{db: db, in: *params, concurrency: defaultBatchConcurrency}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.concurrency < 1 {
+		s.concurrency = defaultBatchConcurrency
+	}
+	if s.in.ReturnConsumedCapacity == "" {
+		s.in.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	}
+	if segments < 1 {
+		segments = 1
+	}
+	for i := 0; i < segments; i++ {
+		s.checkpoints = append(s.checkpoints, Checkpoint{Segment: i})
+	}
+	return s
+}
+
+// Resume makes the scan continue from the checkpoints of the previous scan with the same number of segments.
+func (s *ParallelScan) Resume(checkpoints []Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(checkpoints) != len(s.checkpoints) {
+		return fmt.Errorf("scan has %d segments, got %d checkpoints", len(s.checkpoints), len(checkpoints))
+	}
+	resumed := make([]Checkpoint, len(checkpoints))
+	seen := make(map[int]bool, len(checkpoints))
+	for _, c := range checkpoints {
+		if c.Segment < 0 || c.Segment >= len(resumed) || seen[c.Segment] {
+			return fmt.Errorf("invalid checkpoint of segment %d", c.Segment)
+		}
+		seen[c.Segment] = true
+		resumed[c.Segment] = c
+	}
+	s.checkpoints = resumed
+	return nil
+}
+
+// Checkpoints returns positions of all the segments.
+func (s *ParallelScan) Checkpoints() []Checkpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Checkpoint(nil), s.checkpoints...)
+}
+
+// Progress returns progress of the scan since Run started.
+func (s *ParallelScan) Progress() ScanProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.progress
+	switch {
+	case !s.stopped.IsZero():
+		p.Elapsed = s.stopped.Sub(s.started)
+	case !s.started.IsZero():
+		p.Elapsed = time.Since(s.started)
+	}
+	return p
+}
+
+// Run scans segments, that are not done yet, and calls fn with every page. fn is called concurrently
+// for pages of different segments. The first error, of fn or of Scan, stops all of the segments.
+// Progress is reported every interval set with WithProgress, and once the scan stops.
+func (s *ParallelScan) Run(ctx context.Context, fn func(segment int, page Page) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.mu.Lock()
+	s.started, s.stopped = time.Now(), time.Time{}
+	s.progress = ScanProgress{TotalSegments: len(s.checkpoints)}
+	var pending []int
+	for _, c := range s.checkpoints {
+		if c.Done {
+			s.progress.SegmentsDone++
+		} else {
+			pending = append(pending, c.Segment)
+		}
+	}
+	s.mu.Unlock()
+
+	stop := make(chan struct{})
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+		if s.report == nil {
+			return
+		}
+		if s.progressInterval > 0 {
+			ticker := time.NewTicker(s.progressInterval)
+			defer ticker.Stop()
+			for running := true; running; {
+				select {
+				case <-ticker.C:
+					s.report(s.Progress())
+				case <-stop:
+					running = false
+				}
+			}
+		} else {
+			<-stop
+		}
+		s.report(s.Progress())
+	}()
+
+	segments := make(chan int, len(pending))
+	for _, segment := range pending {
+		segments <- segment
+	}
+	close(segments)
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+	)
+	for i := 0; i < s.concurrency && i < len(pending); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for segment := range segments {
+				if err := s.scanSegment(ctx, segment, fn); err != nil {
+					mu.Lock()
+					if first == nil {
+						first = err
+					}
+					mu.Unlock()
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	s.mu.Lock()
+	s.stopped = time.Now()
+	s.mu.Unlock()
+	close(stop)
+	<-reported
+	return first
+}
+
+// Stream scans segments, that are not done yet, and sends their items to the channel, closing it
+// once the scan stops. Items of one page are sent before the checkpoint of its segment moves forward.
+func (s *ParallelScan) Stream(ctx context.Context, items chan<- map[string]types.AttributeValue) error {
+	defer close(items)
+	return s.Run(ctx, func(segment int, page Page) error {
+		for _, item := range page.Items {
+			select {
+			case items <- item:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+}
+
+func (s *ParallelScan) scanSegment(ctx context.Context, segment int, fn func(segment int, page Page) error) error {
+	s.mu.Lock()
+	in := s.in
+	in.Segment = aws.Int32(int32(segment))
+	in.TotalSegments = aws.Int32(int32(len(s.checkpoints)))
+	in.ExclusiveStartKey = s.checkpoints[segment].LastEvaluatedKey
+	s.mu.Unlock()
+
+	p := NewScanPaginator(s.db, &in)
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("could not scan segment %d: %w", segment, err)
+		}
+		if err := fn(segment, page); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		s.checkpoints[segment].LastEvaluatedKey = page.LastEvaluatedKey
+		s.progress.Items += int64(len(page.Items))
+		s.progress.ScannedItems += int64(page.ScannedCount)
+		if page.ConsumedCapacity != nil {
+			s.progress.ConsumedCapacity += aws.ToFloat64(page.ConsumedCapacity.CapacityUnits)
+		}
+		if !p.HasMorePages() {
+			s.checkpoints[segment].Done = true
+			s.progress.SegmentsDone++
+		}
+		s.mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"dynamodb-with-go/pkg/dynamo/memdb"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupScan(t *testing.T, ctx context.Context) *dynamodb.Client {
+	db := setupBatchTables(t, ctx, memdb.New())
+	w := dynamo.NewBatchWriter(ctx, db)
+	for i := 0; i < 200; i++ {
+		assert.NoError(t, w.Put("PartitionKeyTable", map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: strconv.Itoa(i)},
+		}))
+	}
+	assert.NoError(t, w.Close())
+	return db
+}
+
+// scanned collects primary keys of the scanned items.
+type scanned struct {
+	mu   sync.Mutex
+	keys map[string]int
+}
+
+func (s *scanned) add(items []map[string]types.AttributeValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		s.keys = map[string]int{}
+	}
+	for _, item := range items {
+		s.keys[item["pk"].(*types.AttributeValueMemberS).Value]++
+	}
+}
+
+func TestParallelScan(t *testing.T) {
+	ctx := context.Background()
+	db := setupScan(t, ctx)
+	in := &dynamodb.ScanInput{TableName: aws.String("PartitionKeyTable"), Limit: aws.Int32(10)}
+
+	var reports []dynamo.ScanProgress
+	s := dynamo.NewParallelScan(db, in, 8, dynamo.WithScanConcurrency(3), dynamo.WithProgress(0, func(p dynamo.ScanProgress) {
+		reports = append(reports, p)
+	}))
+	var items scanned
+	err := s.Run(ctx, func(segment int, page dynamo.Page) error {
+		items.add(page.Items)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, items.keys, 200)
+	for pk, n := range items.keys {
+		assert.Equal(t, 1, n, pk)
+	}
+	for i, c := range s.Checkpoints() {
+		assert.Equal(t, dynamo.Checkpoint{Segment: i, Done: true}, c)
+	}
+
+	if assert.Len(t, reports, 1) {
+		p := reports[0]
+		assert.Equal(t, int64(200), p.Items)
+		assert.Equal(t, int64(200), p.ScannedItems)
+		assert.Equal(t, 8, p.SegmentsDone)
+		assert.Equal(t, 8, p.TotalSegments)
+		assert.True(t, p.ConsumedCapacity > 0)
+		assert.True(t, p.ItemsPerSecond() > 0)
+		assert.Equal(t, p, s.Progress())
+	}
+	assert.Nil(t, in.Segment, "input should not be modified")
+}
+
+func TestParallelScanStream(t *testing.T) {
+	ctx := context.Background()
+	db := setupScan(t, ctx)
+
+	ch := make(chan map[string]types.AttributeValue)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- dynamo.NewParallelScan(db, &dynamodb.ScanInput{TableName: aws.String("PartitionKeyTable")}, 4).Stream(ctx, ch)
+	}()
+	var items scanned
+	for item := range ch {
+		items.add([]map[string]types.AttributeValue{item})
+	}
+	assert.NoError(t, <-errs)
+	assert.Len(t, items.keys, 200)
+}
+
+func TestParallelScanResume(t *testing.T) {
+	ctx := context.Background()
+	db := setupScan(t, ctx)
+	in := &dynamodb.ScanInput{TableName: aws.String("PartitionKeyTable"), Limit: aws.Int32(5)}
+
+	failed := errors.New("failed")
+	var items scanned
+	var mu sync.Mutex
+	pages := 0
+	s := dynamo.NewParallelScan(db, in, 4, dynamo.WithScanConcurrency(2))
+	err := s.Run(ctx, func(segment int, page dynamo.Page) error {
+		mu.Lock()
+		pages++
+		fail := pages == 10
+		mu.Unlock()
+		if fail {
+			return failed
+		}
+		items.add(page.Items)
+		return nil
+	})
+	assert.Equal(t, failed, err)
+	assert.True(t, len(items.keys) < 200)
+
+	b, err := json.Marshal(s.Checkpoints())
+	assert.NoError(t, err)
+	var checkpoints []dynamo.Checkpoint
+	assert.NoError(t, json.Unmarshal(b, &checkpoints))
+
+	s = dynamo.NewParallelScan(db, in, 4)
+	assert.NoError(t, s.Resume(checkpoints))
+	err = s.Run(ctx, func(segment int, page dynamo.Page) error {
+		items.add(page.Items)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, items.keys, 200)
+	for pk, n := range items.keys {
+		assert.Equal(t, 1, n, pk)
+	}
+
+	assert.Error(t, s.Resume(checkpoints[:3]))
+	assert.Error(t, s.Resume([]dynamo.Checkpoint{{Segment: 0}, {Segment: 0}, {Segment: 1}, {Segment: 2}}))
+}
@@ -0,0 +1,55 @@
+package dynamo
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+// ApplySettings applies properties of the table from CloudFormation template, that cannot be
+// passed to CreateTable: TimeToLiveSpecification, PointInTimeRecoverySpecification and Tags.
+// It needs to be called after table `desc` is created.
+func ApplySettings(ctx context.Context, db *dynamodb.Client, t cloudformation.AWSDynamoDBTable, desc types.TableDescription) error {
+	if ttl := t.TimeToLiveSpecification; ttl != nil && ttl.Enabled {
+		_, err := db.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+			TableName: desc.TableName,
+			TimeToLiveSpecification: &types.TimeToLiveSpecification{
+				AttributeName: aws.String(ttl.AttributeName),
+				Enabled:       aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if pitr := t.PointInTimeRecoverySpecification; pitr != nil && pitr.PointInTimeRecoveryEnabled {
+		_, err := db.UpdateContinuousBackups(ctx, &dynamodb.UpdateContinuousBackupsInput{
+			TableName: desc.TableName,
+			PointInTimeRecoverySpecification: &types.PointInTimeRecoverySpecification{
+				PointInTimeRecoveryEnabled: aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(t.Tags) > 0 {
+		var tags []types.Tag
+		for _, tag := range t.Tags {
+			tags = append(tags, types.Tag{Key: aws.String(tag.Key), Value: aws.String(tag.Value)})
+		}
+		_, err := db.TagResource(ctx, &dynamodb.TagResourceInput{
+			ResourceArn: desc.TableArn,
+			Tags:        tags,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
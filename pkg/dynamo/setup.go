@@ -3,60 +3,102 @@ package dynamo
 import (
 	"context"
 	"testing"
-	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/awslabs/goformation"
+	"github.com/awslabs/goformation/cloudformation"
 )
 
-type EndpointResolver struct{}
-
-func (e EndpointResolver) ResolveEndpoint(region string, options dynamodb.EndpointResolverOptions) (aws.Endpoint, error) {
-	return aws.Endpoint{URL: "http://localhost:8000"}, nil
-}
-
-func localDynamoDB(t *testing.T) *dynamodb.Client {
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion("local"),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("local", "local", "local")),
-	)
-	if err != nil {
-		t.Fatal("could not setup db connection")
+// localDynamoDB connects to the DynamoDB. In-memory DynamoDB is used only when asked for
+// (see WithInMemory), so tests never run against it while meant for DynamoDB local.
+func localDynamoDB(t testing.TB, o options) *dynamodb.Client {
+	if o.err != nil {
+		t.Fatal(o.err)
+	}
+	db, err := newClient(context.Background(), o)
+	if err != nil && !o.endpointSet {
+		t.Fatalf("%v (set %s=%s to run tests against in-memory DynamoDB)", err, EnvEndpoint, InMemory)
 	}
-
-	db := dynamodb.NewFromConfig(cfg, dynamodb.WithEndpointResolver(EndpointResolver{}))
-
-	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
-	defer cancel()
-	_, err = db.ListTables(ctx, nil)
 	if err != nil {
-		t.Fatal("make sure DynamoDB local runs on port :8000", err)
+		t.Fatal(err)
 	}
 	return db
 }
 
-// SetupTable creates table defined in the CloudFormation template file under `path`.
+// LocalClient connects the test to the DynamoDB configured with options and environment variables.
+func LocalClient(t testing.TB, opts ...Option) *dynamodb.Client {
+	return localDynamoDB(t, newOptions(opts))
+}
+
+// SetupTable creates table defined in the CloudFormation template file (or Terraform module) under `path`,
+// looked up by logical name or table name (see LoadTable),
+// and waits until the table and its global secondary indexes are ACTIVE. Items from
+// fixture files (see WithFixtures) are written to the table afterwards.
 // It returns connection to the DynamoDB and cleanup function, that needs to be run after tests.
-func SetupTable(t *testing.T, ctx context.Context, tableName, path string) (*dynamodb.Client, func()) {
-	db := localDynamoDB(t)
-	tmpl, err := goformation.Open(path)
+// Cleanup waits until the table is deleted, so that the next test can create it again.
+func SetupTable(t testing.TB, ctx context.Context, tableName, path string, opts ...Option) (*dynamodb.Client, func()) {
+	o := newOptions(opts)
+	db := localDynamoDB(t, o)
+	table, err := LoadTable(path, tableName, opts...)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	table, err := tmpl.GetAWSDynamoDBTableWithName(tableName)
+	o.rename(t, tableName, &table)
+	err = createTable(ctx, db, table, o)
 	if err != nil {
 		t.Fatal(err)
 	}
-	input := FromCloudFormationToCreateInput(*table)
-	_, err = db.CreateTable(ctx, &input)
+	cleanup := func() {
+		for _, err := range deleteTables(ctx, db, o, table.TableName) {
+			t.Error(err)
+		}
+	}
+	names := map[string]string{tableName: table.TableName}
+	if err := loadFixtures(ctx, db, tableName, names, o.fixtures); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return db, cleanup
+}
+
+// SetupTables creates all tables defined in the CloudFormation template file under `path`.
+// Tables are created concurrently and SetupTables returns once all of them are ACTIVE.
+// It returns connection to the DynamoDB and cleanup function, that deletes all the tables
+// and waits until they are gone.
+func SetupTables(t testing.TB, ctx context.Context, path string, opts ...Option) (*dynamodb.Client, func()) {
+	o := newOptions(opts)
+	db := localDynamoDB(t, o)
+	loaded, err := LoadTables(path, opts...)
 	if err != nil {
 		t.Fatal(err)
 	}
-	return db, func() {
-		db.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(tableName)})
+
+	var (
+		tables  []cloudformation.AWSDynamoDBTable
+		names   = map[string]string{}
+		logical string
+	)
+	for name, table := range loaded {
+		o.rename(t, name, &table)
+		tables = append(tables, table)
+		names[name] = table.TableName
+		logical = name
+	}
+	if len(loaded) > 1 {
+		logical = ""
+	}
+	created, err := createTables(ctx, db, tables, o)
+	cleanup := func() {
+		for _, err := range deleteTables(ctx, db, o, created...) {
+			t.Error(err)
+		}
+	}
+	if err == nil {
+		err = loadFixtures(ctx, db, logical, names, o.fixtures)
+	}
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
 	}
+	return db, cleanup
 }
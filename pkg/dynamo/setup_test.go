@@ -5,6 +5,7 @@ import (
 	"dynamodb-with-go/pkg/dynamo"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
@@ -32,3 +33,94 @@ func TestSetupTable(t *testing.T) {
 	assert.True(t, errors.As(err, &notfound))
 
 }
+
+func TestSetupTableSettings(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := dynamo.SetupTable(t, ctx, "SettingsTable", "./testdata/template.yml")
+	defer cleanup()
+
+	out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String("SettingsTable"),
+	})
+	assert.NoError(t, err)
+	assert.True(t, *out.Table.StreamSpecification.StreamEnabled)
+	assert.Equal(t, types.StreamViewTypeNewAndOldImages, out.Table.StreamSpecification.StreamViewType)
+
+	ttl, err := db.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String("SettingsTable"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "expires_at", *ttl.TimeToLiveDescription.AttributeName)
+	assert.Equal(t, types.TimeToLiveStatusEnabled, ttl.TimeToLiveDescription.TimeToLiveStatus)
+
+	tags, err := db.ListTagsOfResource(ctx, &dynamodb.ListTagsOfResourceInput{
+		ResourceArn: out.Table.TableArn,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Tag{{Key: aws.String("team"), Value: aws.String("sensors")}}, tags.Tags)
+}
+
+func TestSetupTables(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := dynamo.SetupTables(t, ctx, "./testdata/template.yml")
+
+	for _, name := range []string{"PartitionKeyTable", "CompositePrimaryKeyAndManyGlobalIndexTable", "SettingsTable"} {
+		out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(name),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, types.TableStatusActive, out.Table.TableStatus)
+	}
+
+	cleanup()
+	out, err := db.ListTables(ctx, &dynamodb.ListTablesInput{})
+	assert.NoError(t, err)
+	assert.NotContains(t, out.TableNames, "PartitionKeyTable")
+	assert.NotContains(t, out.TableNames, "SettingsTable")
+}
+
+func TestSetupTableWaits(t *testing.T) {
+	ctx := context.Background()
+	tableName := "CompositePrimaryKeyAndManyGlobalIndexTable"
+
+	for i := 0; i < 2; i++ {
+		db, cleanup := dynamo.SetupTable(t, ctx, tableName, "./testdata/template.yml", dynamo.WithTimeout(time.Minute))
+
+		out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(tableName),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, types.TableStatusActive, out.Table.TableStatus)
+		for _, idx := range out.Table.GlobalSecondaryIndexes {
+			assert.Equal(t, types.IndexStatusActive, idx.IndexStatus)
+		}
+		cleanup()
+	}
+}
+
+func TestSetupTableUniqueNames(t *testing.T) {
+	ctx := context.Background()
+
+	for _, name := range []string{"first", "second", "third"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			names := map[string]string{}
+			db, cleanup := dynamo.SetupTable(t, ctx, "PartitionKeyTable", "./testdata/template.yml", dynamo.WithUniqueNames(names))
+			defer cleanup()
+
+			tableName := names["PartitionKeyTable"]
+			assert.Regexp(t, `^PartitionKeyTable-TestSetupTableUniqueNames_`+name+`-[0-9a-f]{8}$`, tableName)
+
+			_, err := db.PutItem(ctx, &dynamodb.PutItemInput{
+				Item:      map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: name}},
+				TableName: aws.String(tableName),
+			})
+			assert.NoError(t, err)
+
+			out, err := db.Scan(ctx, &dynamodb.ScanInput{TableName: aws.String(tableName)})
+			assert.NoError(t, err)
+			assert.Len(t, out.Items, 1)
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package dynamo
 
 import (
+	"sort"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -9,56 +11,159 @@ import (
 
 // FromCloudFormationToCreateInput transforms DynamoDB table from CloudFormation template
 // into CreateTableInput struct, that can be used with aws-sdk-go to create the table.
+//
+// When BillingMode is omitted, table is PROVISIONED if it has ProvisionedThroughput
+// and PAY_PER_REQUEST otherwise. The latter is a local convenience: CloudFormation defaults
+// to PROVISIONED and rejects tables without ProvisionedThroughput.
 func FromCloudFormationToCreateInput(t cloudformation.AWSDynamoDBTable) dynamodb.CreateTableInput {
 	var input dynamodb.CreateTableInput
+	billingMode := billingMode(t)
 	for _, attrs := range t.AttributeDefinitions {
 		input.AttributeDefinitions = append(input.AttributeDefinitions, types.AttributeDefinition{
 			AttributeName: aws.String(attrs.AttributeName),
 			AttributeType: types.ScalarAttributeType(attrs.AttributeType),
 		})
 	}
-	for _, key := range t.KeySchema {
-		input.KeySchema = append(input.KeySchema, types.KeySchemaElement{
-			AttributeName: aws.String(key.AttributeName),
-			KeyType:       types.KeyType(key.KeyType),
-		})
-	}
+	input.KeySchema = keySchema(t.KeySchema)
 	for _, idx := range t.LocalSecondaryIndexes {
-		if idx.Projection.ProjectionType != "ALL" {
-			panic("not implemented")
-		}
-		indexKeySchema := []types.KeySchemaElement{}
-		for _, key := range idx.KeySchema {
-			indexKeySchema = append(indexKeySchema, types.KeySchemaElement{
-				AttributeName: aws.String(key.AttributeName),
-				KeyType:       types.KeyType(key.KeyType),
-			})
-		}
 		input.LocalSecondaryIndexes = append(input.LocalSecondaryIndexes, types.LocalSecondaryIndex{
 			IndexName:  aws.String(idx.IndexName),
-			KeySchema:  indexKeySchema,
-			Projection: &types.Projection{ProjectionType: types.ProjectionType(idx.Projection.ProjectionType)},
+			KeySchema:  keySchema(idx.KeySchema),
+			Projection: projection(idx.Projection),
 		})
 	}
 	for _, idx := range t.GlobalSecondaryIndexes {
-		if idx.Projection.ProjectionType != "ALL" {
-			panic("not implemented")
+		gsi := types.GlobalSecondaryIndex{
+			IndexName:  aws.String(idx.IndexName),
+			KeySchema:  keySchema(idx.KeySchema),
+			Projection: projection(idx.Projection),
 		}
-		indexKeySchema := []types.KeySchemaElement{}
-		for _, key := range idx.KeySchema {
-			indexKeySchema = append(indexKeySchema, types.KeySchemaElement{
-				AttributeName: aws.String(key.AttributeName),
-				KeyType:       types.KeyType(key.KeyType),
-			})
+		if billingMode == types.BillingModeProvisioned {
+			gsi.ProvisionedThroughput = provisionedThroughput(idx.ProvisionedThroughput)
 		}
-		input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, types.GlobalSecondaryIndex{
-			IndexName:  aws.String(idx.IndexName),
-			KeySchema:  indexKeySchema,
-			Projection: &types.Projection{ProjectionType: types.ProjectionType(idx.Projection.ProjectionType)},
-		})
+		input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, gsi)
 	}
 
 	input.TableName = aws.String(t.TableName)
-	input.BillingMode = types.BillingMode(t.BillingMode)
+	input.BillingMode = billingMode
+	if billingMode == types.BillingModeProvisioned {
+		input.ProvisionedThroughput = provisionedThroughput(t.ProvisionedThroughput)
+	}
+	if t.StreamSpecification != nil {
+		input.StreamSpecification = &types.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: types.StreamViewType(t.StreamSpecification.StreamViewType),
+		}
+	}
+	if t.SSESpecification != nil {
+		input.SSESpecification = &types.SSESpecification{
+			Enabled: aws.Bool(t.SSESpecification.SSEEnabled),
+		}
+	}
 	return input
 }
+
+// FromSimpleTableToCreateInput transforms AWS::Serverless::SimpleTable from SAM template
+// into CreateTableInput struct (see FromSimpleTable).
+func FromSimpleTableToCreateInput(t cloudformation.AWSServerlessSimpleTable) dynamodb.CreateTableInput {
+	return FromCloudFormationToCreateInput(FromSimpleTable(t))
+}
+
+// simpleTableKeyTypes maps types of SimpleTable primary key to DynamoDB attribute types.
+var simpleTableKeyTypes = map[string]string{
+	"String": "S",
+	"Number": "N",
+	"Binary": "B",
+}
+
+// FromSimpleTable transforms AWS::Serverless::SimpleTable into AWS::DynamoDB::Table, the way SAM does.
+// Primary key defaults to `id` of type String. Table without ProvisionedThroughput is PAY_PER_REQUEST.
+func FromSimpleTable(t cloudformation.AWSServerlessSimpleTable) cloudformation.AWSDynamoDBTable {
+	key := cloudformation.AWSServerlessSimpleTable_PrimaryKey{Name: "id", Type: "String"}
+	if t.PrimaryKey != nil {
+		if t.PrimaryKey.Name != "" {
+			key.Name = t.PrimaryKey.Name
+		}
+		if t.PrimaryKey.Type != "" {
+			key.Type = t.PrimaryKey.Type
+		}
+	}
+	attrType, ok := simpleTableKeyTypes[key.Type]
+	if !ok {
+		// Let DynamoDB reject invalid type with its own message.
+		attrType = key.Type
+	}
+
+	table := cloudformation.AWSDynamoDBTable{
+		TableName: t.TableName,
+		AttributeDefinitions: []cloudformation.AWSDynamoDBTable_AttributeDefinition{
+			{AttributeName: key.Name, AttributeType: attrType},
+		},
+		KeySchema: []cloudformation.AWSDynamoDBTable_KeySchema{
+			{AttributeName: key.Name, KeyType: "HASH"},
+		},
+		BillingMode: string(types.BillingModePayPerRequest),
+	}
+	if t.ProvisionedThroughput != nil {
+		table.BillingMode = string(types.BillingModeProvisioned)
+		table.ProvisionedThroughput = &cloudformation.AWSDynamoDBTable_ProvisionedThroughput{
+			ReadCapacityUnits:  int64(t.ProvisionedThroughput.ReadCapacityUnits),
+			WriteCapacityUnits: int64(t.ProvisionedThroughput.WriteCapacityUnits),
+		}
+	}
+	if t.SSESpecification != nil {
+		table.SSESpecification = &cloudformation.AWSDynamoDBTable_SSESpecification{
+			SSEEnabled: t.SSESpecification.SSEEnabled,
+		}
+	}
+	for k, v := range t.Tags {
+		table.Tags = append(table.Tags, cloudformation.Tag{Key: k, Value: v})
+	}
+	sort.Slice(table.Tags, func(i, j int) bool { return table.Tags[i].Key < table.Tags[j].Key })
+	return table
+}
+
+func billingMode(t cloudformation.AWSDynamoDBTable) types.BillingMode {
+	if t.BillingMode != "" {
+		return types.BillingMode(t.BillingMode)
+	}
+	if t.ProvisionedThroughput != nil {
+		return types.BillingModeProvisioned
+	}
+	return types.BillingModePayPerRequest
+}
+
+func provisionedThroughput(p *cloudformation.AWSDynamoDBTable_ProvisionedThroughput) *types.ProvisionedThroughput {
+	if p == nil {
+		return nil
+	}
+	return &types.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(p.ReadCapacityUnits),
+		WriteCapacityUnits: aws.Int64(p.WriteCapacityUnits),
+	}
+}
+
+func keySchema(keys []cloudformation.AWSDynamoDBTable_KeySchema) []types.KeySchemaElement {
+	schema := []types.KeySchemaElement{}
+	for _, key := range keys {
+		schema = append(schema, types.KeySchemaElement{
+			AttributeName: aws.String(key.AttributeName),
+			KeyType:       types.KeyType(key.KeyType),
+		})
+	}
+	return schema
+}
+
+// projection translates index projection. NonKeyAttributes are only meaningful
+// for INCLUDE projection, but they are copied as they are, so that DynamoDB
+// can reject invalid combination the same way CloudFormation would.
+func projection(p *cloudformation.AWSDynamoDBTable_Projection) *types.Projection {
+	if p == nil {
+		return nil
+	}
+	ret := &types.Projection{ProjectionType: types.ProjectionType(p.ProjectionType)}
+	if len(p.NonKeyAttributes) > 0 {
+		ret.NonKeyAttributes = append([]string{}, p.NonKeyAttributes...)
+	}
+	return ret
+}
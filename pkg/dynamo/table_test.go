@@ -1,6 +1,7 @@
 package dynamo_test
 
 import (
+	"context"
 	"dynamodb-with-go/pkg/dynamo"
 	"testing"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/awslabs/goformation"
+	"github.com/awslabs/goformation/cloudformation"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -347,4 +349,274 @@ func TestTable(t *testing.T) {
 			TableName: aws.String("CompositePrimaryKeyAndManyGlobalIndexTable"),
 		}, input)
 	})
+
+	t.Run("table with keys only projections", func(t *testing.T) {
+		tmpl, err := goformation.Open("./testdata/template.yml")
+		assert.NoError(t, err)
+
+		table, err := tmpl.GetAWSDynamoDBTableWithName("KeysOnlyProjectionTable")
+		assert.NoError(t, err)
+
+		input := dynamo.FromCloudFormationToCreateInput(*table)
+		assert.Equal(t, []types.LocalSecondaryIndex{
+			{
+				IndexName: aws.String("LocalKeysOnly"),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String("pk"),
+						KeyType:       types.KeyTypeHash,
+					},
+					{
+						AttributeName: aws.String("lsi_sk"),
+						KeyType:       types.KeyTypeRange,
+					},
+				},
+				Projection: &types.Projection{
+					ProjectionType: types.ProjectionTypeKeysOnly,
+				},
+			},
+		}, input.LocalSecondaryIndexes)
+		assert.Equal(t, []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String("GlobalKeysOnly"),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String("gsi_pk"),
+						KeyType:       types.KeyTypeHash,
+					},
+				},
+				Projection: &types.Projection{
+					ProjectionType: types.ProjectionTypeKeysOnly,
+				},
+			},
+		}, input.GlobalSecondaryIndexes)
+	})
+
+	t.Run("table with include projections", func(t *testing.T) {
+		tmpl, err := goformation.Open("./testdata/template.yml")
+		assert.NoError(t, err)
+
+		table, err := tmpl.GetAWSDynamoDBTableWithName("IncludeProjectionTable")
+		assert.NoError(t, err)
+
+		input := dynamo.FromCloudFormationToCreateInput(*table)
+		assert.Equal(t, []types.LocalSecondaryIndex{
+			{
+				IndexName: aws.String("LocalInclude"),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String("pk"),
+						KeyType:       types.KeyTypeHash,
+					},
+					{
+						AttributeName: aws.String("lsi_sk"),
+						KeyType:       types.KeyTypeRange,
+					},
+				},
+				Projection: &types.Projection{
+					ProjectionType:   types.ProjectionTypeInclude,
+					NonKeyAttributes: []string{"name"},
+				},
+			},
+			{
+				IndexName: aws.String("LocalAll"),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String("pk"),
+						KeyType:       types.KeyTypeHash,
+					},
+					{
+						AttributeName: aws.String("gsi_sk"),
+						KeyType:       types.KeyTypeRange,
+					},
+				},
+				Projection: &types.Projection{
+					ProjectionType: types.ProjectionTypeAll,
+				},
+			},
+		}, input.LocalSecondaryIndexes)
+		assert.Equal(t, []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String("GlobalInclude"),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String("gsi_pk"),
+						KeyType:       types.KeyTypeHash,
+					},
+					{
+						AttributeName: aws.String("gsi_sk"),
+						KeyType:       types.KeyTypeRange,
+					},
+				},
+				Projection: &types.Projection{
+					ProjectionType:   types.ProjectionTypeInclude,
+					NonKeyAttributes: []string{"name", "size"},
+				},
+			},
+		}, input.GlobalSecondaryIndexes)
+	})
+
+	t.Run("table with provisioned throughput", func(t *testing.T) {
+		tmpl, err := goformation.Open("./testdata/template.yml")
+		assert.NoError(t, err)
+
+		table, err := tmpl.GetAWSDynamoDBTableWithName("ProvisionedTable")
+		assert.NoError(t, err)
+
+		input := dynamo.FromCloudFormationToCreateInput(*table)
+		assert.Equal(t, dynamodb.CreateTableInput{
+			AttributeDefinitions: []types.AttributeDefinition{
+				{
+					AttributeName: aws.String("pk"),
+					AttributeType: types.ScalarAttributeTypeS,
+				},
+				{
+					AttributeName: aws.String("gsi_pk"),
+					AttributeType: types.ScalarAttributeTypeS,
+				},
+			},
+			BillingMode: types.BillingModeProvisioned,
+			KeySchema: []types.KeySchemaElement{
+				{
+					AttributeName: aws.String("pk"),
+					KeyType:       types.KeyTypeHash,
+				},
+			},
+			GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+				{
+					IndexName: aws.String("GlobalSecondaryIndex1"),
+					KeySchema: []types.KeySchemaElement{
+						{
+							AttributeName: aws.String("gsi_pk"),
+							KeyType:       types.KeyTypeHash,
+						},
+					},
+					Projection: &types.Projection{
+						ProjectionType: types.ProjectionTypeAll,
+					},
+					ProvisionedThroughput: &types.ProvisionedThroughput{
+						ReadCapacityUnits:  aws.Int64(3),
+						WriteCapacityUnits: aws.Int64(4),
+					},
+				},
+			},
+			ProvisionedThroughput: &types.ProvisionedThroughput{
+				ReadCapacityUnits:  aws.Int64(5),
+				WriteCapacityUnits: aws.Int64(10),
+			},
+			TableName: aws.String("ProvisionedTable"),
+		}, input)
+	})
+
+	t.Run("table without billing mode", func(t *testing.T) {
+		tmpl, err := goformation.Open("./testdata/template.yml")
+		assert.NoError(t, err)
+
+		table, err := tmpl.GetAWSDynamoDBTableWithName("DefaultBillingModeTable")
+		assert.NoError(t, err)
+
+		input := dynamo.FromCloudFormationToCreateInput(*table)
+		assert.Equal(t, types.BillingModeProvisioned, input.BillingMode)
+		assert.Equal(t, &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(1),
+			WriteCapacityUnits: aws.Int64(2),
+		}, input.ProvisionedThroughput)
+
+		table.ProvisionedThroughput = nil
+		input = dynamo.FromCloudFormationToCreateInput(*table)
+		assert.Equal(t, types.BillingModePayPerRequest, input.BillingMode)
+		assert.Nil(t, input.ProvisionedThroughput)
+	})
+
+	t.Run("table with stream and server side encryption", func(t *testing.T) {
+		tmpl, err := goformation.Open("./testdata/template.yml")
+		assert.NoError(t, err)
+
+		table, err := tmpl.GetAWSDynamoDBTableWithName("SettingsTable")
+		assert.NoError(t, err)
+
+		input := dynamo.FromCloudFormationToCreateInput(*table)
+		assert.Equal(t, &types.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: types.StreamViewTypeNewAndOldImages,
+		}, input.StreamSpecification)
+		assert.Equal(t, &types.SSESpecification{
+			Enabled: aws.Bool(true),
+		}, input.SSESpecification)
+	})
+}
+
+func TestSimpleTable(t *testing.T) {
+	tables, err := dynamo.LoadTables("./testdata/sam.yml")
+	assert.NoError(t, err)
+	assert.Len(t, tables, 4)
+
+	t.Run("default primary key, on-demand", func(t *testing.T) {
+		assert.Equal(t, dynamodb.CreateTableInput{
+			AttributeDefinitions: []types.AttributeDefinition{
+				{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS},
+			},
+			BillingMode: types.BillingModePayPerRequest,
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash},
+			},
+			TableName: aws.String("DefaultKeyTable"),
+		}, dynamo.FromCloudFormationToCreateInput(tables["DefaultKeyTable"]))
+	})
+
+	t.Run("primary key, provisioned throughput and SSE", func(t *testing.T) {
+		assert.Equal(t, dynamodb.CreateTableInput{
+			AttributeDefinitions: []types.AttributeDefinition{
+				{AttributeName: aws.String("order_id"), AttributeType: types.ScalarAttributeTypeN},
+			},
+			BillingMode: types.BillingModeProvisioned,
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("order_id"), KeyType: types.KeyTypeHash},
+			},
+			ProvisionedThroughput: &types.ProvisionedThroughput{
+				ReadCapacityUnits:  aws.Int64(5),
+				WriteCapacityUnits: aws.Int64(2),
+			},
+			SSESpecification: &types.SSESpecification{Enabled: aws.Bool(true)},
+			TableName:        aws.String("SimpleOrdersTable"),
+		}, dynamo.FromCloudFormationToCreateInput(tables["OrdersTable"]))
+		assert.Equal(t, []cloudformation.Tag{{Key: "env", Value: "test"}, {Key: "team", Value: "orders"}}, tables["OrdersTable"].Tags)
+	})
+
+	t.Run("binary primary key", func(t *testing.T) {
+		input := dynamo.FromCloudFormationToCreateInput(tables["SessionsTable"])
+		assert.Equal(t, []types.AttributeDefinition{
+			{AttributeName: aws.String("token"), AttributeType: types.ScalarAttributeTypeB},
+		}, input.AttributeDefinitions)
+	})
+}
+
+func TestJSONAndYAMLTemplates(t *testing.T) {
+	for _, name := range []string{"template", "sam"} {
+		fromYAML, err := dynamo.LoadTables("./testdata/" + name + ".yml")
+		assert.NoError(t, err)
+		fromJSON, err := dynamo.LoadTables("./testdata/" + name + ".json")
+		assert.NoError(t, err)
+
+		assert.Len(t, fromJSON, len(fromYAML))
+		for logical, table := range fromYAML {
+			assert.Equal(t,
+				dynamo.FromCloudFormationToCreateInput(table),
+				dynamo.FromCloudFormationToCreateInput(fromJSON[logical]),
+				"%s: %s", name, logical)
+			assert.Equal(t, table, fromJSON[logical], "%s: %s", name, logical)
+		}
+	}
+}
+
+func TestSetupSimpleTable(t *testing.T) {
+	ctx := context.Background()
+	names := map[string]string{}
+	db, cleanup := dynamo.SetupTable(t, ctx, "OrdersTable", "./testdata/sam.json", dynamo.WithUniqueNames(names))
+	defer cleanup()
+
+	out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(names["OrdersTable"])})
+	assert.NoError(t, err)
+	assert.Equal(t, "order_id", *out.Table.KeySchema[0].AttributeName)
+	assert.Equal(t, types.TableStatusActive, out.Table.TableStatus)
 }
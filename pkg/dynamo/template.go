@@ -0,0 +1,708 @@
+package dynamo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/awslabs/goformation/intrinsics"
+)
+
+// tableTypes are types of resources, that define DynamoDB tables.
+var tableTypes = map[string]bool{
+	"AWS::DynamoDB::Table":         true,
+	"AWS::Serverless::SimpleTable": true,
+}
+
+// readTables reads DynamoDB tables from CloudFormation template. Parameters, conditions
+// and intrinsic functions used in properties of the tables are resolved (see resolver),
+// tables with false Condition are left out. Tables, that can't be resolved, are kept as errors.
+func readTables(path string, o options) (loaded, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return loaded{}, err
+	}
+	if !strings.HasSuffix(path, ".json") {
+		// Converts YAML to JSON and short form of intrinsic functions (e.g. !Ref) to the long one.
+		data, err = intrinsics.ProcessYAML(data, &intrinsics.ProcessorOptions{NoProcess: true})
+		if err != nil {
+			return loaded{}, err
+		}
+	}
+	var tmpl struct {
+		Parameters map[string]map[string]interface{}
+		Mappings   map[string]map[string]map[string]interface{}
+		Conditions map[string]interface{}
+		Resources  map[string]map[string]interface{}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&tmpl); err != nil {
+		return loaded{}, fmt.Errorf("invalid template %s: %w", path, err)
+	}
+
+	r, err := newResolver(tmpl.Parameters, tmpl.Mappings, tmpl.Conditions, tmpl.Resources, o)
+	if err != nil {
+		return loaded{}, fmt.Errorf("template %s: %w", path, err)
+	}
+	l := loaded{path: path, tables: map[string]cloudformation.AWSDynamoDBTable{}, invalid: map[string]error{}}
+	for name, res := range tmpl.Resources {
+		typ, _ := res["Type"].(string)
+		if !tableTypes[typ] {
+			continue
+		}
+		table, create, err := r.table(name, typ, res)
+		if err != nil {
+			l.invalid[name] = fmt.Errorf("template %s, resource %s: %w", path, name, err)
+			continue
+		}
+		if create {
+			l.tables[name] = table
+		}
+	}
+	return l, nil
+}
+
+// table resolves properties of the table resource. It reports whether the table is created,
+// that is whether its Condition is true.
+func (r *resolver) table(name, typ string, res map[string]interface{}) (cloudformation.AWSDynamoDBTable, bool, error) {
+	if cond, ok := res["Condition"].(string); ok {
+		create, err := r.condition(cond)
+		if err != nil || !create {
+			return cloudformation.AWSDynamoDBTable{}, false, err
+		}
+	}
+	props, _, err := r.resolve(res["Properties"])
+	if err == nil {
+		err = checkResolved(props)
+	}
+	if err != nil {
+		return cloudformation.AWSDynamoDBTable{}, false, err
+	}
+	table, err := decodeTable(typ, props)
+	if err != nil {
+		return cloudformation.AWSDynamoDBTable{}, false, err
+	}
+	if table.TableName == "" {
+		table.TableName = name
+	}
+	return table, true, nil
+}
+
+// decodeTable decodes resolved properties of the table. Unknown properties are errors, like in CloudFormation.
+func decodeTable(typ string, props interface{}) (cloudformation.AWSDynamoDBTable, error) {
+	if props == nil {
+		props = map[string]interface{}{}
+	}
+	b, err := json.Marshal(props)
+	if err != nil {
+		return cloudformation.AWSDynamoDBTable{}, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if typ == "AWS::Serverless::SimpleTable" {
+		// Types without methods decode properties only, not the whole resource.
+		type properties cloudformation.AWSServerlessSimpleTable
+		var simple properties
+		if err := dec.Decode(&simple); err != nil {
+			return cloudformation.AWSDynamoDBTable{}, err
+		}
+		return FromSimpleTable(cloudformation.AWSServerlessSimpleTable(simple)), nil
+	}
+	type properties cloudformation.AWSDynamoDBTable
+	var table properties
+	if err := dec.Decode(&table); err != nil {
+		return cloudformation.AWSDynamoDBTable{}, err
+	}
+	return cloudformation.AWSDynamoDBTable(table), nil
+}
+
+// resolver evaluates intrinsic functions of the template: Ref, Fn::Sub, Fn::Join, Fn::If, Fn::FindInMap,
+// Fn::Select, Fn::Split, Fn::Base64 and the condition functions Fn::Equals, Fn::Not, Fn::And and Fn::Or.
+// Values known only once the stack is deployed, like Fn::GetAtt, Fn::ImportValue or Ref to other resource,
+// are unresolved, which is an error only when they end up in properties of the table.
+type resolver struct {
+	params map[string]string
+	// types of the parameters declared in the template.
+	types      map[string]string
+	mappings   map[string]map[string]map[string]interface{}
+	resources  map[string]map[string]interface{}
+	conditions map[string]interface{}
+	evaluated  map[string]bool
+	evaluating map[string]bool
+}
+
+// unresolved is the value, that can't be known without deploying the stack, described by the
+// function that returned it.
+type unresolved string
+
+func newResolver(params map[string]map[string]interface{}, mappings map[string]map[string]map[string]interface{},
+	conditions map[string]interface{}, resources map[string]map[string]interface{}, o options) (*resolver, error) {
+	r := &resolver{
+		params: map[string]string{
+			"AWS::AccountId": "000000000000",
+			"AWS::Partition": "aws",
+			"AWS::Region":    o.region,
+			"AWS::StackId":   "arn:aws:cloudformation:" + o.region + ":000000000000:stack/local/00000000-0000-0000-0000-000000000000",
+			"AWS::StackName": "local",
+			"AWS::URLSuffix": "amazonaws.com",
+		},
+		types:      map[string]string{},
+		mappings:   mappings,
+		resources:  resources,
+		conditions: conditions,
+		evaluated:  map[string]bool{},
+		evaluating: map[string]bool{},
+	}
+	for name, p := range params {
+		r.types[name], _ = p["Type"].(string)
+		if def, ok := p["Default"]; ok {
+			s, err := toString(def)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %s: %w", name, err)
+			}
+			r.params[name] = s
+		}
+	}
+	for name, value := range o.parameters {
+		if _, ok := params[name]; !ok && !strings.HasPrefix(name, "AWS::") {
+			return nil, fmt.Errorf("parameter %s is not defined", name)
+		}
+		r.params[name] = value
+	}
+	return r, nil
+}
+
+// resolve evaluates intrinsic functions in `v`. It reports whether the value is AWS::NoValue,
+// in which case the property (or element of the list) is removed.
+func (r *resolver) resolve(v interface{}) (interface{}, bool, error) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if len(x) == 1 {
+			for fn, arg := range x {
+				if fn == "Ref" || strings.HasPrefix(fn, "Fn::") {
+					return r.call(fn, arg)
+				}
+			}
+		}
+		// Properties are resolved in order, so that the same error is reported every time.
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make(map[string]interface{}, len(x))
+		for _, k := range keys {
+			resolved, noValue, err := r.resolve(x[k])
+			if err != nil {
+				return nil, false, fmt.Errorf("%s: %w", k, err)
+			}
+			if !noValue {
+				out[k] = resolved
+			}
+		}
+		return out, false, nil
+	case []interface{}:
+		out := make([]interface{}, 0, len(x))
+		for i, e := range x {
+			resolved, noValue, err := r.resolve(e)
+			if err != nil {
+				return nil, false, fmt.Errorf("[%d]: %w", i, err)
+			}
+			if !noValue {
+				out = append(out, resolved)
+			}
+		}
+		return out, false, nil
+	default:
+		return v, false, nil
+	}
+}
+
+func (r *resolver) call(fn string, arg interface{}) (interface{}, bool, error) {
+	switch fn {
+	case "Ref":
+		name, ok := arg.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("Ref expects name, got %v", arg)
+		}
+		if name == "AWS::NoValue" {
+			return nil, true, nil
+		}
+		v, err := r.ref(name)
+		return v, false, err
+	case "Fn::Sub":
+		v, err := r.sub(arg)
+		return v, false, err
+	case "Fn::Join":
+		v, err := r.join(arg)
+		return v, false, err
+	case "Fn::If":
+		args, ok := arg.([]interface{})
+		if !ok || len(args) != 3 {
+			return nil, false, fmt.Errorf("Fn::If expects [condition, value if true, value if false], got %v", arg)
+		}
+		name, ok := args[0].(string)
+		if !ok {
+			return nil, false, fmt.Errorf("Fn::If expects name of the condition, got %v", args[0])
+		}
+		cond, err := r.condition(name)
+		if err != nil {
+			return nil, false, err
+		}
+		if cond {
+			return r.resolve(args[1])
+		}
+		return r.resolve(args[2])
+	case "Fn::FindInMap":
+		v, err := r.findInMap(arg)
+		return v, false, err
+	case "Fn::Select":
+		v, err := r.selectElem(arg)
+		return v, false, err
+	case "Fn::Split":
+		v, err := r.split(arg)
+		return v, false, err
+	case "Fn::Base64":
+		v, _, err := r.resolve(arg)
+		if err != nil {
+			return nil, false, fmt.Errorf("Fn::Base64: %w", err)
+		}
+		if u, ok := v.(unresolved); ok {
+			return u, false, nil
+		}
+		s, err := toString(v)
+		if err != nil {
+			return nil, false, fmt.Errorf("Fn::Base64: %w", err)
+		}
+		return base64.StdEncoding.EncodeToString([]byte(s)), false, nil
+	default:
+		// Fn::GetAtt, Fn::ImportValue, Fn::GetAZs, Fn::Cidr and others depend on the deployed stack.
+		return unresolved(fn), false, nil
+	}
+}
+
+func (r *resolver) ref(name string) (interface{}, error) {
+	v, ok := r.params[name]
+	if !ok {
+		if _, declared := r.types[name]; declared {
+			return nil, fmt.Errorf("parameter %s has no value, set it with WithParameters", name)
+		}
+		if _, ok := r.resources[name]; ok {
+			return unresolved("Ref to resource " + name), nil
+		}
+		return nil, fmt.Errorf("Ref to %s, that is neither parameter nor resource", name)
+	}
+	switch typ := r.types[name]; {
+	case typ == "Number":
+		// Number parameters are used in properties like ReadCapacityUnits, that must be numbers.
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return nil, fmt.Errorf("parameter %s must be a number, got %q", name, v)
+		}
+		return json.Number(v), nil
+	case typ != "CommaDelimitedList" && !strings.HasPrefix(typ, "List<"):
+		return v, nil
+	}
+	var list []interface{}
+	for _, e := range strings.Split(v, ",") {
+		list = append(list, strings.TrimSpace(e))
+	}
+	return list, nil
+}
+
+// args resolves arguments of the function, that expects a list of n values.
+func (r *resolver) args(fn string, arg interface{}, n int) ([]interface{}, error) {
+	args, ok := arg.([]interface{})
+	if !ok || len(args) != n {
+		return nil, fmt.Errorf("%s expects %d arguments, got %v", fn, n, arg)
+	}
+	resolved := make([]interface{}, n)
+	for i, a := range args {
+		v, _, err := r.resolve(a)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		resolved[i] = v
+	}
+	return resolved, nil
+}
+
+func (r *resolver) findInMap(arg interface{}) (interface{}, error) {
+	args, err := r.args("Fn::FindInMap", arg, 3)
+	if err != nil {
+		return nil, err
+	}
+	var keys [3]string
+	for i, a := range args {
+		if u, ok := a.(unresolved); ok {
+			return u, nil
+		}
+		if keys[i], err = toString(a); err != nil {
+			return nil, fmt.Errorf("Fn::FindInMap: %w", err)
+		}
+	}
+	v, ok := r.mappings[keys[0]][keys[1]][keys[2]]
+	if !ok {
+		return nil, fmt.Errorf("Fn::FindInMap: mapping %s has no %s.%s", keys[0], keys[1], keys[2])
+	}
+	return v, nil
+}
+
+func (r *resolver) selectElem(arg interface{}) (interface{}, error) {
+	args, err := r.args("Fn::Select", arg, 2)
+	if err != nil {
+		return nil, err
+	}
+	index, err := toString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("Fn::Select: %w", err)
+	}
+	i, err := strconv.Atoi(index)
+	if err != nil {
+		return nil, fmt.Errorf("Fn::Select expects index, got %q", index)
+	}
+	if u, ok := args[1].(unresolved); ok {
+		return u, nil
+	}
+	list, ok := args[1].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Fn::Select expects list, got %v", args[1])
+	}
+	if i < 0 || i >= len(list) {
+		return nil, fmt.Errorf("Fn::Select index %d out of range of %d elements", i, len(list))
+	}
+	return list[i], nil
+}
+
+func (r *resolver) split(arg interface{}) (interface{}, error) {
+	args, err := r.args("Fn::Split", arg, 2)
+	if err != nil {
+		return nil, err
+	}
+	delimiter, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("Fn::Split expects string delimiter, got %v", args[0])
+	}
+	if u, ok := args[1].(unresolved); ok {
+		return u, nil
+	}
+	s, err := toString(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("Fn::Split: %w", err)
+	}
+	var list []interface{}
+	for _, e := range strings.Split(s, delimiter) {
+		list = append(list, e)
+	}
+	return list, nil
+}
+
+func (r *resolver) sub(arg interface{}) (interface{}, error) {
+	format, vars := arg, map[string]interface{}{}
+	if args, ok := arg.([]interface{}); ok {
+		if len(args) != 2 {
+			return "", fmt.Errorf("Fn::Sub expects [string, variables], got %v", arg)
+		}
+		format = args[0]
+		if vars, ok = args[1].(map[string]interface{}); !ok {
+			return "", fmt.Errorf("Fn::Sub expects map of variables, got %v", args[1])
+		}
+	}
+	s, ok := format.(string)
+	if !ok {
+		return "", fmt.Errorf("Fn::Sub expects string, got %v", format)
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("Fn::Sub: unclosed variable in %q", s)
+		}
+		end += start
+		b.WriteString(s[:start])
+		name := s[start+2 : end]
+		s = s[end+1:]
+
+		if strings.HasPrefix(name, "!") {
+			b.WriteString("${" + name[1:] + "}")
+			continue
+		}
+		var v interface{}
+		if raw, ok := vars[name]; ok {
+			resolved, _, err := r.resolve(raw)
+			if err != nil {
+				return "", fmt.Errorf("Fn::Sub variable %s: %w", name, err)
+			}
+			v = resolved
+		} else if i := strings.Index(name, "."); i > 0 {
+			// ${Resource.Attribute} is Fn::GetAtt, known once the resource is deployed.
+			if _, ok := r.resources[name[:i]]; !ok {
+				return "", fmt.Errorf("Fn::Sub: %s refers to %s, that is not a resource", name, name[:i])
+			}
+			v = unresolved("Fn::GetAtt " + name)
+		} else {
+			resolved, err := r.ref(name)
+			if err != nil {
+				return "", fmt.Errorf("Fn::Sub: %w", err)
+			}
+			v = resolved
+		}
+		if u, ok := v.(unresolved); ok {
+			return u, nil
+		}
+		str, err := toString(v)
+		if err != nil {
+			return "", fmt.Errorf("Fn::Sub variable %s: %w", name, err)
+		}
+		b.WriteString(str)
+	}
+}
+
+func (r *resolver) join(arg interface{}) (interface{}, error) {
+	args, ok := arg.([]interface{})
+	if !ok || len(args) != 2 {
+		return "", fmt.Errorf("Fn::Join expects [delimiter, list], got %v", arg)
+	}
+	delimiter, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("Fn::Join expects string delimiter, got %v", args[0])
+	}
+	resolved, _, err := r.resolve(args[1])
+	if err != nil {
+		return "", fmt.Errorf("Fn::Join: %w", err)
+	}
+	if u, ok := resolved.(unresolved); ok {
+		return u, nil
+	}
+	list, ok := resolved.([]interface{})
+	if !ok {
+		return "", fmt.Errorf("Fn::Join expects list, got %v", resolved)
+	}
+	elems := make([]string, 0, len(list))
+	for _, e := range list {
+		if u, ok := e.(unresolved); ok {
+			return u, nil
+		}
+		s, err := toString(e)
+		if err != nil {
+			return "", fmt.Errorf("Fn::Join: %w", err)
+		}
+		elems = append(elems, s)
+	}
+	return strings.Join(elems, delimiter), nil
+}
+
+// condition evaluates condition from the Conditions section of the template.
+func (r *resolver) condition(name string) (bool, error) {
+	if v, ok := r.evaluated[name]; ok {
+		return v, nil
+	}
+	expr, ok := r.conditions[name]
+	if !ok {
+		return false, fmt.Errorf("condition %s is not defined", name)
+	}
+	if r.evaluating[name] {
+		return false, fmt.Errorf("condition %s refers to itself", name)
+	}
+	r.evaluating[name] = true
+	v, err := r.evaluate(expr)
+	delete(r.evaluating, name)
+	if err != nil {
+		return false, fmt.Errorf("condition %s: %w", name, err)
+	}
+	r.evaluated[name] = v
+	return v, nil
+}
+
+func (r *resolver) evaluate(expr interface{}) (bool, error) {
+	// Short form !Condition of YAML templates is read as a plain name of the condition.
+	if name, ok := expr.(string); ok {
+		return r.condition(name)
+	}
+	m, ok := expr.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return false, fmt.Errorf("expected condition function, got %v", expr)
+	}
+	for fn, arg := range m {
+		if fn == "Condition" {
+			name, ok := arg.(string)
+			if !ok {
+				return false, fmt.Errorf("Condition expects name, got %v", arg)
+			}
+			return r.condition(name)
+		}
+		args, ok := arg.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s expects list, got %v", fn, arg)
+		}
+		switch fn {
+		case "Fn::Equals":
+			if len(args) != 2 {
+				return false, fmt.Errorf("Fn::Equals expects 2 values, got %d", len(args))
+			}
+			var values [2]string
+			for i, a := range args {
+				resolved, _, err := r.resolve(a)
+				if err != nil {
+					return false, err
+				}
+				if values[i], err = toString(resolved); err != nil {
+					return false, err
+				}
+			}
+			return values[0] == values[1], nil
+		case "Fn::Not":
+			if len(args) != 1 {
+				return false, fmt.Errorf("Fn::Not expects 1 condition, got %d", len(args))
+			}
+			v, err := r.evaluate(args[0])
+			return !v, err
+		case "Fn::And", "Fn::Or":
+			and := fn == "Fn::And"
+			for _, a := range args {
+				v, err := r.evaluate(a)
+				if err != nil {
+					return false, err
+				}
+				if v != and {
+					return v, nil
+				}
+			}
+			return and, nil
+		default:
+			return false, fmt.Errorf("condition function %s is not supported", fn)
+		}
+	}
+	return false, nil
+}
+
+func toString(v interface{}) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case json.Number:
+		return x.String(), nil
+	case bool:
+		if x {
+			return "true", nil
+		}
+		return "false", nil
+	case []interface{}:
+		// List parameters are comma delimited strings.
+		elems := make([]string, 0, len(x))
+		for _, e := range x {
+			s, err := toString(e)
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, s)
+		}
+		return strings.Join(elems, ","), nil
+	case unresolved:
+		return "", fmt.Errorf("%s can't be resolved without deploying the stack", x)
+	default:
+		return "", fmt.Errorf("expected string, got %v", v)
+	}
+}
+
+// checkResolved returns error when the value has unresolved parts, that would end up in the table.
+func checkResolved(v interface{}) error {
+	switch x := v.(type) {
+	case unresolved:
+		return fmt.Errorf("%s can't be resolved without deploying the stack", x)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := checkResolved(x[k]); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+		}
+	case []interface{}:
+		for i, e := range x {
+			if err := checkResolved(e); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// findTable looks the table up by its logical ID and, when there is no such resource,
+// by its physical name. It returns logical ID of the table.
+func findTable(tables map[string]cloudformation.AWSDynamoDBTable, name, path string) (string, error) {
+	if _, ok := tables[name]; ok {
+		return name, nil
+	}
+	var found []string
+	for logical, table := range tables {
+		if table.TableName == name {
+			found = append(found, logical)
+		}
+	}
+	sort.Strings(found)
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("table %q not found in %s", name, path)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("table %q is defined by many resources in %s: %s", name, path, strings.Join(found, ", "))
+	}
+}
+
+// loaded are DynamoDB tables read from the template, keyed by logical IDs. Tables, that could not be
+// resolved, e.g. because their properties use Fn::GetAtt, are kept as errors reported only when
+// such table is requested.
+type loaded struct {
+	path    string
+	tables  map[string]cloudformation.AWSDynamoDBTable
+	invalid map[string]error
+}
+
+// pick returns tables looked up by logical IDs or table names (see findTable), keyed by logical IDs.
+// Without names, it returns all the tables.
+func (l loaded) pick(names ...string) (map[string]cloudformation.AWSDynamoDBTable, error) {
+	var invalid []string
+	for name := range l.invalid {
+		invalid = append(invalid, name)
+	}
+	sort.Strings(invalid)
+	if len(names) == 0 {
+		if len(invalid) > 0 {
+			return nil, l.invalid[invalid[0]]
+		}
+		return l.tables, nil
+	}
+
+	picked := make(map[string]cloudformation.AWSDynamoDBTable, len(names))
+	for _, name := range names {
+		if err, ok := l.invalid[name]; ok {
+			return nil, err
+		}
+		logical, err := findTable(l.tables, name, l.path)
+		if err != nil && len(invalid) > 0 {
+			return nil, fmt.Errorf("%w, tables %s could not be resolved", err, strings.Join(invalid, ", "))
+		}
+		if err != nil {
+			return nil, err
+		}
+		picked[logical] = l.tables[logical]
+	}
+	return picked, nil
+}
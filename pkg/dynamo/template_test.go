@@ -0,0 +1,110 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateParameters(t *testing.T) {
+	t.Run("default values", func(t *testing.T) {
+		tables, err := dynamo.LoadTables("./testdata/parameters.yml", dynamo.WithParameters(map[string]string{"Team": "sensors"}))
+		assert.NoError(t, err)
+		assert.Len(t, tables, 2)
+
+		orders := tables["OrdersTable"]
+		assert.Equal(t, "dev-orders", orders.TableName)
+		assert.Len(t, orders.AttributeDefinitions, 1)
+		assert.Empty(t, orders.GlobalSecondaryIndexes)
+		assert.Equal(t, int64(5), orders.ProvisionedThroughput.ReadCapacityUnits)
+		assert.Equal(t, "dev-sensors-${literal}", tables["TeamTable"].TableName)
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		tables, err := dynamo.LoadTables("./testdata/parameters.yml", dynamo.WithRegion("eu-west-1"), dynamo.WithParameters(map[string]string{
+			"Env":          "prod",
+			"Team":         "sensors",
+			"ReadCapacity": "10",
+		}))
+		assert.NoError(t, err)
+		assert.Len(t, tables, 3)
+
+		orders := tables["OrdersTable"]
+		assert.Equal(t, "prod-orders", orders.TableName)
+		assert.Len(t, orders.AttributeDefinitions, 2)
+		if assert.Len(t, orders.GlobalSecondaryIndexes, 1) {
+			assert.Equal(t, "Search", orders.GlobalSecondaryIndexes[0].IndexName)
+			assert.Equal(t, int64(10), orders.GlobalSecondaryIndexes[0].ProvisionedThroughput.ReadCapacityUnits)
+		}
+		assert.Equal(t, "prod-audit-eu-west-1", tables["AuditTable"].TableName)
+	})
+
+	t.Run("lookup by table name", func(t *testing.T) {
+		table, err := dynamo.LoadTable("./testdata/parameters.yml", "qa-orders", dynamo.WithParameters(map[string]string{
+			"Env":    "qa",
+			"Team":   "sensors",
+			"Search": "true",
+		}))
+		assert.NoError(t, err)
+		assert.Len(t, table.GlobalSecondaryIndexes, 1)
+	})
+
+	t.Run("missing parameter", func(t *testing.T) {
+		_, err := dynamo.LoadTables("./testdata/parameters.yml")
+		assert.EqualError(t, err, "template ./testdata/parameters.yml, resource TeamTable: TableName: Fn::Sub variable Name: parameter Team has no value, set it with WithParameters")
+	})
+
+	t.Run("invalid number", func(t *testing.T) {
+		_, err := dynamo.LoadTables("./testdata/parameters.yml", dynamo.WithParameters(map[string]string{"Team": "sensors", "ReadCapacity": "many"}))
+		assert.EqualError(t, err, "template ./testdata/parameters.yml, resource OrdersTable: ProvisionedThroughput: ReadCapacityUnits: parameter ReadCapacity must be a number, got \"many\"")
+	})
+
+	t.Run("unknown parameter", func(t *testing.T) {
+		_, err := dynamo.LoadTables("./testdata/parameters.yml", dynamo.WithParameters(map[string]string{"Stage": "prod"}))
+		assert.EqualError(t, err, "template ./testdata/parameters.yml: parameter Stage is not defined")
+	})
+}
+
+func TestTemplateIntrinsics(t *testing.T) {
+	table, err := dynamo.LoadTable("./testdata/intrinsics.yml", "OrdersTable", dynamo.WithRegion("eu-west-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "local-dev-orders-eu-west-1", table.TableName)
+	if assert.Len(t, table.KeySchema, 2) {
+		assert.Equal(t, "pk", table.KeySchema[0].AttributeName)
+		assert.Equal(t, "sk", table.KeySchema[1].AttributeName)
+	}
+	assert.Equal(t, int64(1), table.ProvisionedThroughput.ReadCapacityUnits)
+	assert.Equal(t, int64(2), table.ProvisionedThroughput.WriteCapacityUnits)
+	assert.Equal(t, "000000000000", table.Tags[0].Value)
+	assert.Equal(t, "none", table.Tags[1].Value)
+
+	table, err = dynamo.LoadTable("./testdata/intrinsics.yml", "local-prod-orders-local", dynamo.WithParameters(map[string]string{"Env": "prod"}))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), table.ProvisionedThroughput.ReadCapacityUnits)
+
+	_, err = dynamo.LoadTable("./testdata/intrinsics.yml", "ImportedTable")
+	assert.EqualError(t, err, "template ./testdata/intrinsics.yml, resource ImportedTable: TableName: Fn::ImportValue can't be resolved without deploying the stack")
+	_, err = dynamo.LoadTables("./testdata/intrinsics.yml")
+	assert.EqualError(t, err, "template ./testdata/intrinsics.yml, resource ImportedTable: TableName: Fn::ImportValue can't be resolved without deploying the stack")
+	_, err = dynamo.LoadTable("./testdata/intrinsics.yml", "other-table")
+	assert.EqualError(t, err, `table "other-table" not found in ./testdata/intrinsics.yml, tables ImportedTable, StreamTable could not be resolved`)
+	_, err = dynamo.LoadTable("./testdata/intrinsics.yml", "StreamTable")
+	assert.EqualError(t, err, "template ./testdata/intrinsics.yml, resource StreamTable: Tags: [0]: Value: Fn::GetAtt Stream.Arn can't be resolved without deploying the stack")
+	_, err = dynamo.LoadTable("./testdata/intrinsics.yml", "OrdersTable", dynamo.WithParameters(map[string]string{"Env": "qa"}))
+	assert.EqualError(t, err, "template ./testdata/intrinsics.yml, resource OrdersTable: ProvisionedThroughput: ReadCapacityUnits: Fn::FindInMap: mapping Capacity has no qa.Read")
+}
+
+func TestSetupTableWithParameters(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := dynamo.SetupTable(t, ctx, "test-orders", "./testdata/parameters.yml",
+		dynamo.WithParameters(map[string]string{"Env": "test", "Team": "sensors", "Search": "true"}))
+	defer cleanup()
+
+	out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("test-orders")})
+	assert.NoError(t, err)
+	assert.Len(t, out.Table.GlobalSecondaryIndexes, 1)
+}
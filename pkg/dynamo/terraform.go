@@ -0,0 +1,431 @@
+package dynamo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// TerraformTable is aws_dynamodb_table resource of Terraform.
+// See https://registry.terraform.io/providers/hashicorp/aws/latest/docs/resources/dynamodb_table
+type TerraformTable struct {
+	Name                   string                 `hcl:"name,optional"`
+	BillingMode            string                 `hcl:"billing_mode,optional"`
+	HashKey                string                 `hcl:"hash_key,optional"`
+	RangeKey               string                 `hcl:"range_key,optional"`
+	ReadCapacity           int64                  `hcl:"read_capacity,optional"`
+	WriteCapacity          int64                  `hcl:"write_capacity,optional"`
+	Attributes             []TerraformAttribute   `hcl:"attribute,block"`
+	GlobalSecondaryIndexes []TerraformGlobalIndex `hcl:"global_secondary_index,block"`
+	LocalSecondaryIndexes  []TerraformLocalIndex  `hcl:"local_secondary_index,block"`
+	TTL                    *TerraformTTL          `hcl:"ttl,block"`
+	StreamEnabled          bool                   `hcl:"stream_enabled,optional"`
+	StreamViewType         string                 `hcl:"stream_view_type,optional"`
+	PointInTimeRecovery    *TerraformEnabled      `hcl:"point_in_time_recovery,block"`
+	ServerSideEncryption   *TerraformEnabled      `hcl:"server_side_encryption,block"`
+	Tags                   map[string]string      `hcl:"tags,optional"`
+}
+
+// TerraformAttribute is attribute block of aws_dynamodb_table.
+type TerraformAttribute struct {
+	Name string `hcl:"name,optional"`
+	Type string `hcl:"type,optional"`
+}
+
+// TerraformGlobalIndex is global_secondary_index block of aws_dynamodb_table.
+type TerraformGlobalIndex struct {
+	Name             string   `hcl:"name,optional"`
+	HashKey          string   `hcl:"hash_key,optional"`
+	RangeKey         string   `hcl:"range_key,optional"`
+	ProjectionType   string   `hcl:"projection_type,optional"`
+	NonKeyAttributes []string `hcl:"non_key_attributes,optional"`
+	ReadCapacity     int64    `hcl:"read_capacity,optional"`
+	WriteCapacity    int64    `hcl:"write_capacity,optional"`
+}
+
+// TerraformLocalIndex is local_secondary_index block of aws_dynamodb_table.
+// Its partition key is the one of the table.
+type TerraformLocalIndex struct {
+	Name             string   `hcl:"name,optional"`
+	RangeKey         string   `hcl:"range_key,optional"`
+	ProjectionType   string   `hcl:"projection_type,optional"`
+	NonKeyAttributes []string `hcl:"non_key_attributes,optional"`
+}
+
+// TerraformTTL is ttl block of aws_dynamodb_table.
+type TerraformTTL struct {
+	AttributeName string `hcl:"attribute_name,optional"`
+	Enabled       bool   `hcl:"enabled,optional"`
+}
+
+// TerraformEnabled is a block with `enabled` flag only, e.g. point_in_time_recovery.
+type TerraformEnabled struct {
+	Enabled bool `hcl:"enabled,optional"`
+}
+
+// FromTerraformToCreateInput transforms aws_dynamodb_table resource of Terraform
+// into CreateTableInput struct (see FromTerraform).
+func FromTerraformToCreateInput(t TerraformTable) dynamodb.CreateTableInput {
+	return FromCloudFormationToCreateInput(FromTerraform(t))
+}
+
+// FromTerraform transforms aws_dynamodb_table resource of Terraform into AWS::DynamoDB::Table,
+// so that it is created and compared with existing tables the same way. Like in Terraform,
+// table is PROVISIONED unless billing_mode says otherwise.
+func FromTerraform(t TerraformTable) cloudformation.AWSDynamoDBTable {
+	table := cloudformation.AWSDynamoDBTable{
+		TableName:   t.Name,
+		BillingMode: t.BillingMode,
+		KeySchema:   terraformKeySchema(t.HashKey, t.RangeKey),
+	}
+	if table.BillingMode == "" {
+		table.BillingMode = string(types.BillingModeProvisioned)
+	}
+	provisioned := table.BillingMode == string(types.BillingModeProvisioned)
+	if provisioned {
+		table.ProvisionedThroughput = &cloudformation.AWSDynamoDBTable_ProvisionedThroughput{
+			ReadCapacityUnits:  t.ReadCapacity,
+			WriteCapacityUnits: t.WriteCapacity,
+		}
+	}
+	for _, attr := range t.Attributes {
+		table.AttributeDefinitions = append(table.AttributeDefinitions, cloudformation.AWSDynamoDBTable_AttributeDefinition{
+			AttributeName: attr.Name,
+			AttributeType: attr.Type,
+		})
+	}
+	for _, idx := range t.GlobalSecondaryIndexes {
+		gsi := cloudformation.AWSDynamoDBTable_GlobalSecondaryIndex{
+			IndexName:  idx.Name,
+			KeySchema:  terraformKeySchema(idx.HashKey, idx.RangeKey),
+			Projection: terraformProjection(idx.ProjectionType, idx.NonKeyAttributes),
+		}
+		if provisioned {
+			gsi.ProvisionedThroughput = &cloudformation.AWSDynamoDBTable_ProvisionedThroughput{
+				ReadCapacityUnits:  idx.ReadCapacity,
+				WriteCapacityUnits: idx.WriteCapacity,
+			}
+		}
+		table.GlobalSecondaryIndexes = append(table.GlobalSecondaryIndexes, gsi)
+	}
+	for _, idx := range t.LocalSecondaryIndexes {
+		table.LocalSecondaryIndexes = append(table.LocalSecondaryIndexes, cloudformation.AWSDynamoDBTable_LocalSecondaryIndex{
+			IndexName:  idx.Name,
+			KeySchema:  terraformKeySchema(t.HashKey, idx.RangeKey),
+			Projection: terraformProjection(idx.ProjectionType, idx.NonKeyAttributes),
+		})
+	}
+	if t.TTL != nil && t.TTL.AttributeName != "" {
+		table.TimeToLiveSpecification = &cloudformation.AWSDynamoDBTable_TimeToLiveSpecification{
+			AttributeName: t.TTL.AttributeName,
+			Enabled:       t.TTL.Enabled,
+		}
+	}
+	if t.StreamEnabled {
+		table.StreamSpecification = &cloudformation.AWSDynamoDBTable_StreamSpecification{
+			StreamViewType: t.StreamViewType,
+		}
+	}
+	if t.PointInTimeRecovery != nil {
+		table.PointInTimeRecoverySpecification = &cloudformation.AWSDynamoDBTable_PointInTimeRecoverySpecification{
+			PointInTimeRecoveryEnabled: t.PointInTimeRecovery.Enabled,
+		}
+	}
+	if t.ServerSideEncryption != nil {
+		table.SSESpecification = &cloudformation.AWSDynamoDBTable_SSESpecification{
+			SSEEnabled: t.ServerSideEncryption.Enabled,
+		}
+	}
+	for k, v := range t.Tags {
+		table.Tags = append(table.Tags, cloudformation.Tag{Key: k, Value: v})
+	}
+	sort.Slice(table.Tags, func(i, j int) bool { return table.Tags[i].Key < table.Tags[j].Key })
+	return table
+}
+
+func terraformKeySchema(hashKey, rangeKey string) []cloudformation.AWSDynamoDBTable_KeySchema {
+	keys := []cloudformation.AWSDynamoDBTable_KeySchema{{AttributeName: hashKey, KeyType: "HASH"}}
+	if rangeKey != "" {
+		keys = append(keys, cloudformation.AWSDynamoDBTable_KeySchema{AttributeName: rangeKey, KeyType: "RANGE"})
+	}
+	return keys
+}
+
+func terraformProjection(projectionType string, nonKeyAttributes []string) *cloudformation.AWSDynamoDBTable_Projection {
+	return &cloudformation.AWSDynamoDBTable_Projection{
+		ProjectionType:   projectionType,
+		NonKeyAttributes: nonKeyAttributes,
+	}
+}
+
+// terraformFunctions are functions of Terraform, that can be used in expressions of the tables.
+var terraformFunctions = map[string]function.Function{
+	"coalesce":   stdlib.CoalesceFunc,
+	"concat":     stdlib.ConcatFunc,
+	"element":    stdlib.ElementFunc,
+	"format":     stdlib.FormatFunc,
+	"join":       stdlib.JoinFunc,
+	"length":     stdlib.LengthFunc,
+	"lookup":     stdlib.LookupFunc,
+	"lower":      stdlib.LowerFunc,
+	"merge":      stdlib.MergeFunc,
+	"replace":    stdlib.ReplaceFunc,
+	"split":      stdlib.SplitFunc,
+	"substr":     stdlib.SubstrFunc,
+	"title":      stdlib.TitleFunc,
+	"trimprefix": stdlib.TrimPrefixFunc,
+	"trimspace":  stdlib.TrimSpaceFunc,
+	"trimsuffix": stdlib.TrimSuffixFunc,
+	"upper":      stdlib.UpperFunc,
+}
+
+// terraformFileSchema lists blocks of Terraform files, that tables depend on.
+var terraformFileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "locals"},
+		{Type: "resource", LabelNames: []string{"type", "name"}},
+	},
+}
+
+// readTerraformTables reads aws_dynamodb_table resources from Terraform files. `path` is the file,
+// glob pattern of files (e.g. ./infra/*.tf) or directory of the module, whose .tf files are read together.
+// Resources are keyed by their names.
+//
+// Expressions are evaluated like in Terraform, with variables (var.*) taking default values or values set
+// with WithParameters, locals (local.*) and common functions, e.g. format or lower. Tables referring
+// to anything else, e.g. other resources or data sources, fail when they are requested.
+func readTerraformTables(path string, o options) (loaded, error) {
+	files, err := terraformFiles(path)
+	if err != nil {
+		return loaded{}, err
+	}
+	parser := hclparse.NewParser()
+	var (
+		variables []*hcl.Block
+		locals    []*hcl.Attribute
+		resources []*hcl.Block
+	)
+	for _, name := range files {
+		file, diags := parser.ParseHCLFile(name)
+		if diags.HasErrors() {
+			return loaded{}, fmt.Errorf("invalid Terraform file %s: %w", name, diags)
+		}
+		content, _, diags := file.Body.PartialContent(terraformFileSchema)
+		if diags.HasErrors() {
+			return loaded{}, fmt.Errorf("invalid Terraform file %s: %w", name, diags)
+		}
+		for _, block := range content.Blocks {
+			switch block.Type {
+			case "variable":
+				variables = append(variables, block)
+			case "locals":
+				attrs, diags := block.Body.JustAttributes()
+				if diags.HasErrors() {
+					return loaded{}, fmt.Errorf("invalid Terraform file %s: %w", name, diags)
+				}
+				for _, attr := range attrs {
+					locals = append(locals, attr)
+				}
+			case "resource":
+				if block.Labels[0] == "aws_dynamodb_table" {
+					resources = append(resources, block)
+				}
+			}
+		}
+	}
+
+	scope, err := newTerraformScope(variables, locals, o)
+	if err != nil {
+		return loaded{}, err
+	}
+	l := loaded{path: path, tables: map[string]cloudformation.AWSDynamoDBTable{}, invalid: map[string]error{}}
+	for _, block := range resources {
+		name := block.Labels[1]
+		if _, ok := l.tables[name]; ok {
+			return loaded{}, fmt.Errorf("%s: aws_dynamodb_table.%s is defined more than once", block.DefRange.Filename, name)
+		}
+		var t TerraformTable
+		if err := decodeTerraformBody(block.Body, scope, &t); err != nil {
+			l.invalid[name] = fmt.Errorf("%s: aws_dynamodb_table.%s: %w", block.DefRange.Filename, name, err)
+			continue
+		}
+		table := FromTerraform(t)
+		if table.TableName == "" {
+			table.TableName = name
+		}
+		l.tables[name] = table
+	}
+	return l, nil
+}
+
+// terraformFiles lists .tf files of the module directory, or files matching the pattern.
+func terraformFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err == nil && info.IsDir() {
+		path = filepath.Join(path, "*.tf")
+	}
+	files, err := filepath.Glob(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Terraform files found in %s", path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// terraformScope is what expressions of the tables can refer to: variables, locals and functions.
+type terraformScope struct {
+	ctx *hcl.EvalContext
+	// unresolved are reasons why locals could not be evaluated.
+	unresolved map[string]error
+}
+
+// newTerraformScope evaluates variables and locals. Locals, that can't be evaluated, are left out
+// and fail only the tables referring to them.
+func newTerraformScope(variables []*hcl.Block, locals []*hcl.Attribute, o options) (terraformScope, error) {
+	vars := map[string]cty.Value{}
+	for _, block := range variables {
+		name := block.Labels[0]
+		attrs, diags := block.Body.JustAttributes()
+		if diags.HasErrors() {
+			return terraformScope{}, fmt.Errorf("%s: variable %s: %w", block.DefRange.Filename, name, diags)
+		}
+		if value, ok := o.parameters[name]; ok {
+			vars[name] = cty.StringVal(value)
+			continue
+		}
+		if def, ok := attrs["default"]; ok {
+			v, diags := def.Expr.Value(nil)
+			if diags.HasErrors() {
+				return terraformScope{}, fmt.Errorf("%s: variable %s: %w", block.DefRange.Filename, name, diags)
+			}
+			vars[name] = v
+		}
+	}
+	s := terraformScope{
+		ctx: &hcl.EvalContext{
+			Variables: map[string]cty.Value{"var": cty.ObjectVal(vars), "local": cty.EmptyObjectVal},
+			Functions: terraformFunctions,
+		},
+		unresolved: map[string]error{},
+	}
+
+	// Locals can refer to each other, so they are evaluated until none of the remaining ones can be.
+	values := map[string]cty.Value{}
+	for pending := locals; len(pending) > 0; {
+		var next []*hcl.Attribute
+		for _, attr := range pending {
+			if err := s.check(attr.Expr); err != nil {
+				s.unresolved[attr.Name] = err
+				next = append(next, attr)
+				continue
+			}
+			v, diags := attr.Expr.Value(s.ctx)
+			if diags.HasErrors() {
+				return terraformScope{}, fmt.Errorf("%s: local.%s: %w", attr.Range.Filename, attr.Name, diags)
+			}
+			delete(s.unresolved, attr.Name)
+			values[attr.Name] = v
+			s.ctx.Variables["local"] = cty.ObjectVal(values)
+		}
+		if len(next) == len(pending) {
+			break
+		}
+		pending = next
+	}
+	return s, nil
+}
+
+// check returns error, when the expression refers to anything, that has no value in the scope.
+func (s terraformScope) check(expr hcl.Expression) error {
+	for _, traversal := range expr.Variables() {
+		root := traversal.RootName()
+		var attr string
+		if len(traversal) > 1 {
+			if a, ok := traversal[1].(hcl.TraverseAttr); ok {
+				attr = a.Name
+			}
+		}
+		obj, ok := s.ctx.Variables[root]
+		switch {
+		case ok && attr != "" && obj.Type().HasAttribute(attr):
+			continue
+		case root == "var":
+			return fmt.Errorf("variable %s has no value, set it with WithParameters", attr)
+		case root == "local" && s.unresolved[attr] != nil:
+			return fmt.Errorf("local.%s: %w", attr, s.unresolved[attr])
+		default:
+			return fmt.Errorf("reference to %s can't be resolved without running Terraform", referenceName(traversal))
+		}
+	}
+	return nil
+}
+
+func referenceName(traversal hcl.Traversal) string {
+	name := traversal.RootName()
+	for _, t := range traversal[1:] {
+		a, ok := t.(hcl.TraverseAttr)
+		if !ok {
+			break
+		}
+		name += "." + a.Name
+	}
+	return name
+}
+
+// decodeTerraformBody decodes the block into struct with gohcl tags. Unlike gohcl.DecodeBody,
+// it skips attributes and blocks, that the struct doesn't have, e.g. lifecycle or timeouts.
+func decodeTerraformBody(body hcl.Body, scope terraformScope, out interface{}) error {
+	schema, _ := gohcl.ImpliedBodySchema(out)
+	content, _, diags := body.PartialContent(schema)
+	if diags.HasErrors() {
+		return diags
+	}
+	v := reflect.ValueOf(out).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		tag := strings.Split(v.Type().Field(i).Tag.Get("hcl"), ",")
+		name, field := tag[0], v.Field(i)
+		if len(tag) < 2 || tag[1] != "block" {
+			attr, ok := content.Attributes[name]
+			if !ok {
+				continue
+			}
+			if err := scope.check(attr.Expr); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if diags := gohcl.DecodeExpression(attr.Expr, scope.ctx, field.Addr().Interface()); diags.HasErrors() {
+				return fmt.Errorf("%s: %w", name, diags)
+			}
+			continue
+		}
+		for _, block := range content.Blocks.OfType(name) {
+			// Blocks are decoded into slices or pointers of structs.
+			elem := reflect.New(field.Type().Elem())
+			if err := decodeTerraformBody(block.Body, scope, elem.Interface()); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if field.Kind() == reflect.Slice {
+				field.Set(reflect.Append(field, elem.Elem()))
+			} else {
+				field.Set(elem)
+			}
+		}
+	}
+	return nil
+}
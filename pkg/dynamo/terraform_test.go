@@ -0,0 +1,128 @@
+package dynamo_test
+
+import (
+	"context"
+	"dynamodb-with-go/pkg/dynamo"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerraform(t *testing.T) {
+	tables, err := dynamo.LoadTables("./testdata/tables.tf")
+	assert.NoError(t, err)
+	assert.Len(t, tables, 2)
+
+	t.Run("provisioned table with indexes", func(t *testing.T) {
+		orders := tables["orders"]
+		assert.Equal(t, dynamodb.CreateTableInput{
+			AttributeDefinitions: []types.AttributeDefinition{
+				{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("sk"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("lsi_sk"), AttributeType: types.ScalarAttributeTypeN},
+				{AttributeName: aws.String("gsi_pk"), AttributeType: types.ScalarAttributeTypeS},
+			},
+			BillingMode: types.BillingModeProvisioned,
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("sk"), KeyType: types.KeyTypeRange},
+			},
+			GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{{
+				IndexName: aws.String("ByCustomer"),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("gsi_pk"), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String("sk"), KeyType: types.KeyTypeRange},
+				},
+				Projection: &types.Projection{
+					ProjectionType:   types.ProjectionTypeInclude,
+					NonKeyAttributes: []string{"total"},
+				},
+				ProvisionedThroughput: &types.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(3),
+					WriteCapacityUnits: aws.Int64(4),
+				},
+			}},
+			LocalSecondaryIndexes: []types.LocalSecondaryIndex{{
+				IndexName: aws.String("ByTotal"),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String("lsi_sk"), KeyType: types.KeyTypeRange},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly},
+			}},
+			ProvisionedThroughput: &types.ProvisionedThroughput{
+				ReadCapacityUnits:  aws.Int64(5),
+				WriteCapacityUnits: aws.Int64(10),
+			},
+			StreamSpecification: &types.StreamSpecification{
+				StreamEnabled:  aws.Bool(true),
+				StreamViewType: types.StreamViewTypeNewAndOldImages,
+			},
+			TableName: aws.String("dev-orders"),
+		}, dynamo.FromCloudFormationToCreateInput(orders))
+
+		assert.Equal(t, &cloudformation.AWSDynamoDBTable_TimeToLiveSpecification{AttributeName: "expires_at", Enabled: true}, orders.TimeToLiveSpecification)
+		assert.True(t, orders.PointInTimeRecoverySpecification.PointInTimeRecoveryEnabled)
+		assert.Equal(t, []cloudformation.Tag{{Key: "team", Value: "orders"}}, orders.Tags)
+	})
+
+	t.Run("on-demand table named after resource", func(t *testing.T) {
+		input := dynamo.FromCloudFormationToCreateInput(tables["settings"])
+		assert.Equal(t, "settings", *input.TableName)
+		assert.Equal(t, types.BillingModePayPerRequest, input.BillingMode)
+		assert.Equal(t, "pk", *input.KeySchema[0].AttributeName)
+		assert.Nil(t, input.ProvisionedThroughput)
+	})
+
+	t.Run("variables", func(t *testing.T) {
+		table, err := dynamo.LoadTable("./testdata/tables.tf", "prod-orders", dynamo.WithParameters(map[string]string{"env": "prod"}))
+		assert.NoError(t, err)
+		assert.Equal(t, "prod-orders", table.TableName)
+
+		_, err = dynamo.LoadTables("./testdata/variables.tf")
+		assert.EqualError(t, err, "./testdata/variables.tf: aws_dynamodb_table.events: name: variable env has no value, set it with WithParameters")
+	})
+}
+
+func TestTerraformModule(t *testing.T) {
+	for _, path := range []string{"./testdata/terraform", "./testdata/terraform/*.tf"} {
+		t.Run(path, func(t *testing.T) {
+			table, err := dynamo.LoadTable(path, "invoices", dynamo.WithParameters(map[string]string{"read_capacity": "7"}))
+			assert.NoError(t, err)
+			assert.Equal(t, "dev-billing-invoices", table.TableName)
+			assert.Equal(t, "id", table.KeySchema[0].AttributeName)
+			assert.Equal(t, "id", table.AttributeDefinitions[0].AttributeName)
+			assert.Equal(t, int64(7), table.ProvisionedThroughput.ReadCapacityUnits)
+			assert.True(t, table.SSESpecification.SSEEnabled)
+			assert.Equal(t, []cloudformation.Tag{
+				{Key: "Env", Value: "dev"},
+				{Key: "Service", Value: "BILLING"},
+				{Key: "Table", Value: "invoices"},
+			}, table.Tags)
+		})
+	}
+
+	_, err := dynamo.LoadTable("./testdata/terraform", "replica")
+	assert.EqualError(t, err, "testdata/terraform/main.tf: aws_dynamodb_table.replica: name: reference to aws_kms_key.invoices.key_id can't be resolved without running Terraform")
+	_, err = dynamo.LoadTables("./testdata/terraform/main.tf")
+	assert.EqualError(t, err, "./testdata/terraform/main.tf: aws_dynamodb_table.invoices: name: local.prefix: variable env has no value, set it with WithParameters")
+}
+
+func TestSetupTableTerraform(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := dynamo.SetupTable(t, ctx, "orders", "./testdata/tables.tf", dynamo.WithParameters(map[string]string{"env": "test"}))
+	defer cleanup()
+
+	out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("test-orders")})
+	assert.NoError(t, err)
+	assert.Len(t, out.Table.GlobalSecondaryIndexes, 1)
+	assert.Len(t, out.Table.LocalSecondaryIndexes, 1)
+
+	ttl, err := db.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String("test-orders")})
+	assert.NoError(t, err)
+	assert.Equal(t, "expires_at", *ttl.TimeToLiveDescription.AttributeName)
+}
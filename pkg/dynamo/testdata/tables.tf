@@ -0,0 +1,92 @@
+variable "env" {
+  type    = string
+  default = "dev"
+}
+
+variable "settings_key" {
+  type    = string
+  default = "pk"
+}
+
+variable "owner" {
+  type = string
+}
+
+provider "aws" {
+  region = "eu-west-1"
+}
+
+resource "aws_dynamodb_table" "orders" {
+  name           = "${var.env}-orders"
+  billing_mode   = "PROVISIONED"
+  read_capacity  = 5
+  write_capacity = 10
+  hash_key       = "pk"
+  range_key      = "sk"
+
+  attribute {
+    name = "pk"
+    type = "S"
+  }
+
+  attribute {
+    name = "sk"
+    type = "S"
+  }
+
+  attribute {
+    name = "lsi_sk"
+    type = "N"
+  }
+
+  attribute {
+    name = "gsi_pk"
+    type = "S"
+  }
+
+  global_secondary_index {
+    name               = "ByCustomer"
+    hash_key           = "gsi_pk"
+    range_key          = "sk"
+    projection_type    = "INCLUDE"
+    non_key_attributes = ["total"]
+    read_capacity      = 3
+    write_capacity     = 4
+  }
+
+  local_secondary_index {
+    name            = "ByTotal"
+    range_key       = "lsi_sk"
+    projection_type = "KEYS_ONLY"
+  }
+
+  ttl {
+    attribute_name = "expires_at"
+    enabled        = true
+  }
+
+  stream_enabled   = true
+  stream_view_type = "NEW_AND_OLD_IMAGES"
+
+  point_in_time_recovery {
+    enabled = true
+  }
+
+  tags = {
+    team = "orders"
+  }
+}
+
+resource "aws_dynamodb_table" "settings" {
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = var.settings_key # HCL 2 expression
+
+  attribute {
+    name = var.settings_key
+    type = "S"
+  }
+}
+
+resource "aws_sqs_queue" "orders" {
+  name = "${var.owner}-orders"
+}
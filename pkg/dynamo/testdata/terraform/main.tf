@@ -0,0 +1,47 @@
+locals {
+  prefix = "${var.env}-${local.service}"
+  service = "billing"
+  key     = "id"
+  tags = {
+    Env     = var.env
+    Service = upper(local.service)
+  }
+}
+
+resource "aws_kms_key" "invoices" {
+  description = "invoices"
+}
+
+resource "aws_dynamodb_table" "invoices" {
+  name           = format("%s-invoices", local.prefix)
+  hash_key       = local.key
+  read_capacity  = var.read_capacity
+  write_capacity = 1
+
+  attribute {
+    name = local.key
+    type = "S"
+  }
+
+  server_side_encryption {
+    enabled     = true
+    kms_key_arn = aws_kms_key.invoices.arn
+  }
+
+  tags = merge(local.tags, { Table = "invoices" })
+
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+
+resource "aws_dynamodb_table" "replica" {
+  name         = "${aws_kms_key.invoices.key_id}-replica"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "id"
+
+  attribute {
+    name = "id"
+    type = "S"
+  }
+}
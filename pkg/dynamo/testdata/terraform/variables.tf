@@ -0,0 +1,9 @@
+variable "env" {
+  type    = string
+  default = "dev"
+}
+
+variable "read_capacity" {
+  type    = number
+  default = 2
+}
@@ -0,0 +1,14 @@
+variable "env" {
+  type = string
+}
+
+resource "aws_dynamodb_table" "events" {
+  name         = "${var.env}-events"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "pk"
+
+  attribute {
+    name = "pk"
+    type = "S"
+  }
+}
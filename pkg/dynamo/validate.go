@@ -0,0 +1,211 @@
+package dynamo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/awslabs/goformation/cloudformation"
+)
+
+const (
+	maxLocalSecondaryIndexes  = 5
+	maxGlobalSecondaryIndexes = 20
+)
+
+// Problems that can be found in the table definition. Every ValidationError wraps one of them,
+// so that they can be matched with errors.Is.
+var (
+	ErrAttributeNotDefined   = errors.New("key attribute is missing from AttributeDefinitions")
+	ErrAttributeNotUsed      = errors.New("attribute is defined but not used by any key schema")
+	ErrDuplicateAttribute    = errors.New("attribute is defined more than once")
+	ErrInvalidAttributeType  = errors.New("attribute type must be one of S, N or B")
+	ErrInvalidKeyType        = errors.New("key type must be HASH or RANGE")
+	ErrInvalidKeySchema      = errors.New("key schema must consist of HASH key optionally followed by RANGE key")
+	ErrLocalIndexHashKey     = errors.New("local secondary index must use hash key of the table")
+	ErrLocalIndexRangeKey    = errors.New("local secondary index requires range key on both table and index")
+	ErrTooManyLocalIndexes   = fmt.Errorf("table can have at most %d local secondary indexes", maxLocalSecondaryIndexes)
+	ErrTooManyGlobalIndexes  = fmt.Errorf("table can have at most %d global secondary indexes", maxGlobalSecondaryIndexes)
+	ErrDuplicateIndex        = errors.New("index name is used more than once")
+	ErrInvalidProjectionType = errors.New("projection type must be one of ALL, KEYS_ONLY or INCLUDE")
+	ErrInvalidBillingMode    = errors.New("billing mode must be PROVISIONED or PAY_PER_REQUEST")
+	ErrMissingThroughput     = errors.New("PROVISIONED billing mode requires ProvisionedThroughput")
+	ErrUnexpectedThroughput  = errors.New("PAY_PER_REQUEST billing mode does not allow ProvisionedThroughput")
+	ErrInvalidThroughput     = errors.New("read and write capacity units must be positive")
+)
+
+// ValidationError is a single problem found in the table definition. Index and Attribute
+// are empty when problem is not related to any particular index or attribute.
+type ValidationError struct {
+	Table     string
+	Index     string
+	Attribute string
+	Err       error
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "table %q", e.Table)
+	if e.Index != "" {
+		fmt.Fprintf(&b, ", index %q", e.Index)
+	}
+	if e.Attribute != "" {
+		fmt.Fprintf(&b, ", attribute %q", e.Attribute)
+	}
+	fmt.Fprintf(&b, ": %s", e.Err)
+	return b.String()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors holds every problem found in the table definition.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// CreateInput validates DynamoDB table from CloudFormation template and transforms it into
+// CreateTableInput. Unlike FromCloudFormationToCreateInput it reports problems, that DynamoDB
+// would reject the table for, as ValidationErrors.
+func CreateInput(t cloudformation.AWSDynamoDBTable) (dynamodb.CreateTableInput, error) {
+	if err := Validate(t); err != nil {
+		return dynamodb.CreateTableInput{}, err
+	}
+	return FromCloudFormationToCreateInput(t), nil
+}
+
+// Validate checks DynamoDB table from CloudFormation template. It returns nil when table is valid,
+// or ValidationErrors describing every problem that was found.
+func Validate(t cloudformation.AWSDynamoDBTable) error {
+	v := validator{table: t.TableName, defined: map[string]bool{}, used: map[string]bool{}}
+
+	for _, attr := range t.AttributeDefinitions {
+		if v.defined[attr.AttributeName] {
+			v.report("", attr.AttributeName, ErrDuplicateAttribute)
+		}
+		v.defined[attr.AttributeName] = true
+		switch attr.AttributeType {
+		case "S", "N", "B":
+		default:
+			v.report("", attr.AttributeName, ErrInvalidAttributeType)
+		}
+	}
+
+	hash, rng := v.keySchema("", t.KeySchema)
+
+	if len(t.LocalSecondaryIndexes) > maxLocalSecondaryIndexes {
+		v.report("", "", ErrTooManyLocalIndexes)
+	}
+	if len(t.GlobalSecondaryIndexes) > maxGlobalSecondaryIndexes {
+		v.report("", "", ErrTooManyGlobalIndexes)
+	}
+
+	indexes := map[string]bool{}
+	for _, idx := range t.LocalSecondaryIndexes {
+		v.index(indexes, idx.IndexName, idx.Projection)
+		idxHash, idxRange := v.keySchema(idx.IndexName, idx.KeySchema)
+		if idxHash != "" && idxHash != hash {
+			v.report(idx.IndexName, idxHash, ErrLocalIndexHashKey)
+		}
+		if rng == "" || idxRange == "" {
+			v.report(idx.IndexName, "", ErrLocalIndexRangeKey)
+		}
+	}
+	for _, idx := range t.GlobalSecondaryIndexes {
+		v.index(indexes, idx.IndexName, idx.Projection)
+		v.keySchema(idx.IndexName, idx.KeySchema)
+	}
+
+	mode := billingMode(t)
+	switch mode {
+	case types.BillingModeProvisioned, types.BillingModePayPerRequest:
+		v.throughput(mode, "", t.ProvisionedThroughput)
+		for _, idx := range t.GlobalSecondaryIndexes {
+			v.throughput(mode, idx.IndexName, idx.ProvisionedThroughput)
+		}
+	default:
+		v.report("", "", ErrInvalidBillingMode)
+	}
+
+	for _, attr := range t.AttributeDefinitions {
+		if !v.used[attr.AttributeName] {
+			v.report("", attr.AttributeName, ErrAttributeNotUsed)
+		}
+	}
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+type validator struct {
+	table   string
+	defined map[string]bool
+	used    map[string]bool
+	errs    ValidationErrors
+}
+
+func (v *validator) report(index, attribute string, err error) {
+	v.errs = append(v.errs, &ValidationError{Table: v.table, Index: index, Attribute: attribute, Err: err})
+}
+
+// keySchema checks key schema of the table or index and returns names of its hash and range keys.
+func (v *validator) keySchema(index string, keys []cloudformation.AWSDynamoDBTable_KeySchema) (hash, rng string) {
+	for i, key := range keys {
+		v.used[key.AttributeName] = true
+		if !v.defined[key.AttributeName] {
+			v.report(index, key.AttributeName, ErrAttributeNotDefined)
+		}
+		switch {
+		case key.KeyType != "HASH" && key.KeyType != "RANGE":
+			v.report(index, key.AttributeName, ErrInvalidKeyType)
+		case i == 0 && key.KeyType == "HASH":
+			hash = key.AttributeName
+		case i == 1 && key.KeyType == "RANGE":
+			rng = key.AttributeName
+		default:
+			v.report(index, key.AttributeName, ErrInvalidKeySchema)
+		}
+	}
+	if len(keys) == 0 {
+		v.report(index, "", ErrInvalidKeySchema)
+	}
+	return hash, rng
+}
+
+func (v *validator) index(seen map[string]bool, name string, p *cloudformation.AWSDynamoDBTable_Projection) {
+	if seen[name] {
+		v.report(name, "", ErrDuplicateIndex)
+	}
+	seen[name] = true
+	if p == nil {
+		v.report(name, "", ErrInvalidProjectionType)
+		return
+	}
+	switch p.ProjectionType {
+	case "ALL", "KEYS_ONLY", "INCLUDE":
+	default:
+		v.report(name, "", ErrInvalidProjectionType)
+	}
+}
+
+func (v *validator) throughput(mode types.BillingMode, index string, p *cloudformation.AWSDynamoDBTable_ProvisionedThroughput) {
+	switch {
+	case mode == types.BillingModePayPerRequest && p != nil:
+		v.report(index, "", ErrUnexpectedThroughput)
+	case mode == types.BillingModeProvisioned && p == nil:
+		v.report(index, "", ErrMissingThroughput)
+	case p != nil && (p.ReadCapacityUnits < 1 || p.WriteCapacityUnits < 1):
+		v.report(index, "", ErrInvalidThroughput)
+	}
+}
@@ -0,0 +1,118 @@
+package dynamo_test
+
+import (
+	"dynamodb-with-go/pkg/dynamo"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/awslabs/goformation"
+	"github.com/awslabs/goformation/cloudformation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("valid tables", func(t *testing.T) {
+		tmpl, err := goformation.Open("./testdata/template.yml")
+		assert.NoError(t, err)
+
+		for name, table := range tmpl.GetAllAWSDynamoDBTableResources() {
+			_, err := dynamo.CreateInput(*table)
+			assert.NoError(t, err, name)
+		}
+	})
+
+	t.Run("key attribute missing, attribute not used", func(t *testing.T) {
+		tmpl, err := goformation.Open("./testdata/invalid.yml")
+		assert.NoError(t, err)
+
+		table, err := tmpl.GetAWSDynamoDBTableWithName("MissingAttributeTable")
+		assert.NoError(t, err)
+
+		_, err = dynamo.CreateInput(*table)
+		assert.Equal(t, dynamo.ValidationErrors{
+			{Table: "MissingAttributeTable", Attribute: "sk", Err: dynamo.ErrAttributeNotDefined},
+			{Table: "MissingAttributeTable", Attribute: "unused", Err: dynamo.ErrAttributeNotUsed},
+		}, err)
+		assert.True(t, errors.Is(err.(dynamo.ValidationErrors)[0], dynamo.ErrAttributeNotDefined))
+		assert.EqualError(t, err,
+			`table "MissingAttributeTable", attribute "sk": key attribute is missing from AttributeDefinitions; `+
+				`table "MissingAttributeTable", attribute "unused": attribute is defined but not used by any key schema`)
+	})
+
+	t.Run("local index with different hash key", func(t *testing.T) {
+		tmpl, err := goformation.Open("./testdata/invalid.yml")
+		assert.NoError(t, err)
+
+		table, err := tmpl.GetAWSDynamoDBTableWithName("LocalIndexHashKeyTable")
+		assert.NoError(t, err)
+
+		err = dynamo.Validate(*table)
+		assert.Equal(t, dynamo.ValidationErrors{
+			{Table: "LocalIndexHashKeyTable", Index: "MyIndex", Attribute: "lsi_pk", Err: dynamo.ErrLocalIndexHashKey},
+		}, err)
+	})
+
+	t.Run("invalid attribute, key and projection types", func(t *testing.T) {
+		tmpl, err := goformation.Open("./testdata/invalid.yml")
+		assert.NoError(t, err)
+
+		table, err := tmpl.GetAWSDynamoDBTableWithName("InvalidTypesTable")
+		assert.NoError(t, err)
+
+		err = dynamo.Validate(*table)
+		assert.Equal(t, dynamo.ValidationErrors{
+			{Table: "InvalidTypesTable", Attribute: "pk", Err: dynamo.ErrInvalidAttributeType},
+			{Table: "InvalidTypesTable", Attribute: "pk", Err: dynamo.ErrInvalidKeyType},
+			{Table: "InvalidTypesTable", Index: "MyIndex", Err: dynamo.ErrInvalidProjectionType},
+		}, err)
+	})
+
+	t.Run("too many indexes", func(t *testing.T) {
+		table := cloudformation.AWSDynamoDBTable{
+			TableName: "TooManyIndexesTable",
+			AttributeDefinitions: []cloudformation.AWSDynamoDBTable_AttributeDefinition{
+				{AttributeName: "pk", AttributeType: "S"},
+				{AttributeName: "sk", AttributeType: "S"},
+			},
+			KeySchema: []cloudformation.AWSDynamoDBTable_KeySchema{
+				{AttributeName: "pk", KeyType: "HASH"},
+				{AttributeName: "sk", KeyType: "RANGE"},
+			},
+		}
+		for i := 0; i < 6; i++ {
+			table.LocalSecondaryIndexes = append(table.LocalSecondaryIndexes, cloudformation.AWSDynamoDBTable_LocalSecondaryIndex{
+				IndexName:  fmt.Sprintf("Local%d", i),
+				KeySchema:  table.KeySchema,
+				Projection: &cloudformation.AWSDynamoDBTable_Projection{ProjectionType: "ALL"},
+			})
+		}
+		for i := 0; i < 21; i++ {
+			table.GlobalSecondaryIndexes = append(table.GlobalSecondaryIndexes, cloudformation.AWSDynamoDBTable_GlobalSecondaryIndex{
+				IndexName:  fmt.Sprintf("Global%d", i),
+				KeySchema:  table.KeySchema,
+				Projection: &cloudformation.AWSDynamoDBTable_Projection{ProjectionType: "ALL"},
+			})
+		}
+
+		err := dynamo.Validate(table)
+		assert.Equal(t, dynamo.ValidationErrors{
+			{Table: "TooManyIndexesTable", Err: dynamo.ErrTooManyLocalIndexes},
+			{Table: "TooManyIndexesTable", Err: dynamo.ErrTooManyGlobalIndexes},
+		}, err)
+	})
+
+	t.Run("provisioned table without throughput", func(t *testing.T) {
+		tmpl, err := goformation.Open("./testdata/invalid.yml")
+		assert.NoError(t, err)
+
+		table, err := tmpl.GetAWSDynamoDBTableWithName("MissingThroughputTable")
+		assert.NoError(t, err)
+
+		err = dynamo.Validate(*table)
+		assert.Equal(t, dynamo.ValidationErrors{
+			{Table: "MissingThroughputTable", Err: dynamo.ErrMissingThroughput},
+			{Table: "MissingThroughputTable", Index: "MyIndex", Err: dynamo.ErrInvalidThroughput},
+		}, err)
+	})
+}
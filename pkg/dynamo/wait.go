@@ -0,0 +1,92 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	minPollInterval = 50 * time.Millisecond
+	maxPollInterval = 2 * time.Second
+)
+
+// waitUntilActive polls DescribeTable until table and all of its global secondary indexes are ACTIVE.
+func waitUntilActive(ctx context.Context, db *dynamodb.Client, name string, timeout time.Duration) (*types.TableDescription, error) {
+	var last *types.TableDescription
+	err := poll(ctx, timeout, func(ctx context.Context) (bool, error) {
+		out, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+		if err != nil {
+			var notFound *types.ResourceNotFoundException
+			if errors.As(err, &notFound) {
+				return false, nil
+			}
+			return false, err
+		}
+		last = out.Table
+		return isActive(out.Table), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("table %q is not ACTIVE: %w", name, err)
+	}
+	return last, nil
+}
+
+func isActive(table *types.TableDescription) bool {
+	if table.TableStatus != types.TableStatusActive {
+		return false
+	}
+	for _, idx := range table.GlobalSecondaryIndexes {
+		if idx.IndexStatus != types.IndexStatusActive {
+			return false
+		}
+	}
+	return true
+}
+
+// waitUntilDeleted polls DescribeTable until table is gone.
+func waitUntilDeleted(ctx context.Context, db *dynamodb.Client, name string, timeout time.Duration) error {
+	err := poll(ctx, timeout, func(ctx context.Context) (bool, error) {
+		_, err := db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return fmt.Errorf("table %q is not deleted: %w", name, err)
+	}
+	return nil
+}
+
+// poll calls done with growing intervals, until it reports success, fails or timeout passes.
+func poll(ctx context.Context, timeout time.Duration, done func(context.Context) (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	interval := minPollInterval
+	for {
+		ok, err := done(ctx)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		interval *= 2
+		if interval > maxPollInterval {
+			interval = maxPollInterval
+		}
+	}
+}